@@ -5,13 +5,16 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"iter"
+	"strings"
 	"time"
 )
 
 const (
-	BankIDURL            = "https://appapi2.bankid.com/rp/v6.0"
-	BankIDTestUrl        = "https://appapi2.test.bankid.com/rp/v6.0"
+	BankIDURL            = BankIDHost + "/rp/v" + string(DefaultAPIVersion)
+	BankIDTestUrl        = BankIDTestHost + "/rp/v" + string(DefaultAPIVersion)
 	BankIDTestPassphrase = "qwerty123"
 )
 
@@ -49,6 +52,21 @@ type BankID interface {
 	// 	- User has signed the document successfully: `status: complete`
 	Sign(ctx context.Context, request SignRequest) (*SignResponse, error)
 
+	// 💳 Initiates a payment order, the user approves a transaction that the BankID app displays natively.
+	// Use the collect method to query the status of the order. If the request is successful the response includes:
+	// 	- orderRef
+	// 	- autoStartToken
+	// 	- qrStartToken
+	// 	- qrStartSecret
+	//
+	// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/payment
+	//
+	// Request flow:
+	// 	- Starting the request: `hintCode: outstandingTransaction`
+	// 	- User needs to provide the pin to approve the transaction: `hintCode: userSign`
+	// 	- User has approved the transaction successfully: `status: complete`
+	Payment(ctx context.Context, request PaymentRequest) (*PaymentResponse, error)
+
 	// 🗝️ Initiates an authentication order when the user is talking to the RP over the phone.
 	// Use the collect method to query the status of the order.
 	//
@@ -76,13 +94,45 @@ type BankID interface {
 	// 🫳 Collects the result of a sign or auth order using orderRef as reference.
 	// RP should keep on calling collect every two seconds if status is pending.
 	// RP must abort if status indicates failed. The user identity is returned when complete.
+	// When the config has a RiskPolicy, completed orders above its risk level are returned as a RiskRejectedError instead.
 	//
 	// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
 	Collect(ctx context.Context, request CollectRequest) (*CollectResponse, error)
 
+	// 🫳 Calls the /collect endpoint every 2 seconds for as long as the order is pending and yields each response.
+	// The sequence ends after the order is `complete` or `failed`. Errors from /collect are yielded to the caller and end the sequence,
+	// if ctx is cancelled the sequence yields ctx.Err() and ends.
+	//
+	// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
+	//
+	// Example:
+	// 		for collectResponse, err := range b.Poll(ctx, authResponse.OrderRef) {
+	// 			if err != nil {
+	// 				return err
+	// 			}
+	// 			// work with CollectResponse
+	// 		}
+	Poll(ctx context.Context, orderRef string) iter.Seq2[*CollectResponse, error]
+
+	// ⏳ Polls the /collect endpoint until the order is no longer pending.
+	// Returns the CompletionData when the order is `complete`, or an OrderFailedError carrying the final HintCode when it `failed`.
+	// Use OnProgress to receive the hint codes of the pending order as they change, e.g. `outstandingTransaction` -> `started` -> `userSign`.
+	//
+	// Example:
+	// 		completionData, err := b.WaitForCompletion(ctx, authResponse.OrderRef, bankid.OnProgress(func(h bankid.HintCode) {
+	// 			// update the user
+	// 		}))
+	//
+	// 		var failed bankid.OrderFailedError
+	// 		if errors.As(err, &failed) && failed.HintCode == bankid.UserCancel {
+	// 			// the user cancelled
+	// 		}
+	WaitForCompletion(ctx context.Context, orderRef string, opts ...WaitOption) (*CompletionData, error)
+
 	// 🫳 Continuously calls the /collect endpoint (every 2 seconds) in a goroutine for as long as the order is pending
 	// Collects the result of a sign or auth order using orderRef as reference
 	// Will result in a succeeded or failed authentication. The user identity is returned when complete.
+	// The channel is closed when the order is no longer pending, ctx is cancelled or /collect returns an error, use Poll to receive the error.
 	//
 	// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
 	//
@@ -103,6 +153,14 @@ type BankID interface {
 	// 		}
 	CollectRoutine(ctx context.Context, request CollectRequest, response chan *CollectResponse)
 
+	// 🪪 Verifies the QR code of a person's BankID digital ID card and returns the identity of the holder.
+	// The holder shows the ID card in the BankID app, use it where the holder is physically present, e.g. at a check-in counter.
+	// QR codes that BankID can't verify are returned as an IDCardNotVerifiedError. Other invalidParameters errors,
+	// e.g. a malformed request, are errors of the RP and are returned as a BankIDError that matches ErrInvalidParameters.
+	//
+	// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/verify
+	Verify(ctx context.Context, request VerifyRequest) (*VerifyResponse, error)
+
 	// ✋ Cancels an ongoing sign or auth order.
 	// This is typically used if the user cancels the order in your service or app.
 	//
@@ -110,8 +168,14 @@ type BankID interface {
 	Cancel(ctx context.Context, request CancelRequest) (*CancelResponse, error)
 }
 
+// The interval between calls to /collect recommended by BankID.
+const collectInterval = 2 * time.Second
+
 type bankid struct {
 	config *RequestConfig
+
+	// the interval between calls to /collect while polling, defaults to collectInterval
+	pollInterval time.Duration
 }
 
 func New(config Config) (BankID, error) {
@@ -128,10 +192,25 @@ func New(config Config) (BankID, error) {
 	}
 
 	return &bankid{
-		config: c,
+		config:       c,
+		pollInterval: collectInterval,
 	}, nil
 }
 
+// WaitOption configures WaitForCompletion
+type WaitOption func(*waitOptions)
+
+type waitOptions struct {
+	progress func(HintCode)
+}
+
+// OnProgress calls f every time the hint code of the pending order changes.
+func OnProgress(f func(HintCode)) WaitOption {
+	return func(o *waitOptions) {
+		o.progress = f
+	}
+}
+
 // Returns a default Test BankID interface with SSL/CA certificates and password
 func NewTestDefault() (BankID, error) {
 	config := Config{
@@ -149,7 +228,8 @@ func NewTestDefault() (BankID, error) {
 	}
 
 	return &bankid{
-		config: c,
+		config:       c,
+		pollInterval: collectInterval,
 	}, nil
 }
 
@@ -158,6 +238,8 @@ func NewTestDefault() (BankID, error) {
 //
 //	`bankid.qrStartToken.time.qrAuthCode`
 //
+// Use a QRSession to keep track of the time since the order was started.
+//
 // BankID documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/qrkoder
 func GenerateQrPayload(qrStartSecret string, qrStartToken string, timeInSeconds int) (string, error) {
 	hash := hmac.New(sha256.New, []byte(qrStartSecret))
@@ -174,6 +256,8 @@ func (b *bankid) Auth(ctx context.Context, req AuthRequest) (*AuthResponse, erro
 		validateRequired(req),
 		validateEndUserIP(req.EndUserIP),
 		validateRequirement(req.Requirement),
+		validateReturnURL(req.ReturnURL),
+		validateDevice(req.App, req.Web),
 	)
 	if err != nil {
 		return nil, err
@@ -183,6 +267,7 @@ func (b *bankid) Auth(ctx context.Context, req AuthRequest) (*AuthResponse, erro
 		processUserVisibleData(req.UserVisibleData),
 		processUserNonVisibleData(req.UserNonVisibleData),
 		processUserVisibleDataFormat(req.UserVisibleDataFormat),
+		processPersonalNumber(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("process error: %w", err)
@@ -201,6 +286,8 @@ func (b *bankid) Sign(ctx context.Context, req SignRequest) (*SignResponse, erro
 		validateRequired(req),
 		validateEndUserIP(req.EndUserIP),
 		validateRequirement(req.Requirement),
+		validateReturnURL(req.ReturnURL),
+		validateDevice(req.App, req.Web),
 	)
 	if err != nil {
 		return nil, err
@@ -210,6 +297,7 @@ func (b *bankid) Sign(ctx context.Context, req SignRequest) (*SignResponse, erro
 		processUserVisibleData(req.UserVisibleData),
 		processUserNonVisibleData(req.UserNonVisibleData),
 		processUserVisibleDataFormat(req.UserVisibleDataFormat),
+		processPersonalNumber(),
 	)
 	if err != nil {
 		return nil, err
@@ -222,6 +310,38 @@ func (b *bankid) Sign(ctx context.Context, req SignRequest) (*SignResponse, erro
 	})
 }
 
+// Initiates a payment order. Use the collect method to query the status of the order.
+func (b *bankid) Payment(ctx context.Context, req PaymentRequest) (*PaymentResponse, error) {
+	err := validate(
+		validateRequired(req),
+		validateEndUserIP(req.EndUserIP),
+		validateTransaction(req.UserVisibleTransaction),
+		validateRiskFlags(req.RiskFlags),
+		validateRequirement(req.Requirement),
+		validateReturnURL(req.ReturnURL),
+		validateDevice(req.App, req.Web),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = process[PaymentRequest](req,
+		processUserVisibleData(req.UserVisibleData),
+		processUserNonVisibleData(req.UserNonVisibleData),
+		processUserVisibleDataFormat(req.UserVisibleDataFormat),
+		processPersonalNumber(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("process error: %w", err)
+	}
+
+	return request[PaymentResponse](ctx, RequestParameters{
+		Path:   "/payment",
+		Config: b.config,
+		Body:   req,
+	})
+}
+
 // Initiates an authentication order when the user is talking to the RP over the phone.
 func (b *bankid) PhoneAuth(ctx context.Context, req PhoneAuthRequest) (*PhoneAuthResponse, error) {
 	err := validate(
@@ -238,6 +358,7 @@ func (b *bankid) PhoneAuth(ctx context.Context, req PhoneAuthRequest) (*PhoneAut
 		processUserVisibleData(req.UserVisibleData),
 		processUserNonVisibleData(req.UserNonVisibleData),
 		processUserVisibleDataFormat(req.UserVisibleDataFormat),
+		processPersonalNumber(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("process error: %w", err)
@@ -266,6 +387,7 @@ func (b *bankid) PhoneSign(ctx context.Context, req PhoneSignRequest) (*PhoneSig
 		processUserVisibleData(req.UserVisibleData),
 		processUserNonVisibleData(req.UserNonVisibleData),
 		processUserVisibleDataFormat(req.UserVisibleDataFormat),
+		processPersonalNumber(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("process error: %w", err)
@@ -278,6 +400,34 @@ func (b *bankid) PhoneSign(ctx context.Context, req PhoneSignRequest) (*PhoneSig
 	})
 }
 
+// Verifies the QR code of a digital ID card.
+func (b *bankid) Verify(ctx context.Context, req VerifyRequest) (*VerifyResponse, error) {
+	err := validate(
+		validateIDCardQRCode(req.QRCode),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyResponse, err := request[VerifyResponse](ctx, RequestParameters{
+		Path:   "/verify",
+		Config: b.config,
+		Body:   req,
+	})
+
+	// BankID rejects QR codes it can't verify as invalid parameters with details about the qrCode,
+	// other invalid parameters are errors of the RP
+	var bankIDError BankIDError
+	if errors.As(err, &bankIDError) && bankIDError.ErrorCode == InvalidParameters && strings.Contains(strings.ToLower(bankIDError.Details), "qrcode") {
+		return nil, IDCardNotVerifiedError{Details: bankIDError.Details, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return verifyResponse, nil
+}
+
 // Cancels an ongoing sign or auth order.
 func (b *bankid) Cancel(ctx context.Context, req CancelRequest) (*CancelResponse, error) {
 	return request[CancelResponse](ctx, RequestParameters{
@@ -289,36 +439,106 @@ func (b *bankid) Cancel(ctx context.Context, req CancelRequest) (*CancelResponse
 
 // Collects the result of a sign or auth order using orderRef as reference.
 func (b *bankid) Collect(ctx context.Context, req CollectRequest) (*CollectResponse, error) {
-	return request[CollectResponse](ctx, RequestParameters{
+	collectResponse, err := request[CollectResponse](ctx, RequestParameters{
 		Path:   "/collect",
 		Config: b.config,
 		Body:   req,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	// withhold completions that the risk policy doesn't accept
+	if b.config.RiskPolicy != nil {
+		err = b.config.RiskPolicy.check(collectResponse)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return collectResponse, nil
 }
 
-// A goroutine that checks the /collect endpoint every 2 seconds and returns the response in a channel
+// Yields the response of the /collect endpoint every 2 seconds until the order is no longer pending
 // BankID reference: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
-func (b *bankid) CollectRoutine(ctx context.Context, request CollectRequest, response chan *CollectResponse) {
-	defer close(response)
+func (b *bankid) Poll(ctx context.Context, orderRef string) iter.Seq2[*CollectResponse, error] {
+	return func(yield func(*CollectResponse, error) bool) {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			case <-timer.C:
+			}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			collectResponse, err := b.Collect(ctx, request)
+			collectResponse, err := b.Collect(ctx, CollectRequest{
+				OrderRef: orderRef,
+			})
 			if err != nil {
-				fmt.Printf("Error collecting status: %v\n", err)
+				yield(nil, err)
 				return
 			}
 
-			response <- collectResponse
+			if !yield(collectResponse, nil) || collectResponse.Status != Pending {
+				return
+			}
+
+			timer.Reset(b.pollInterval)
+		}
+	}
+}
+
+// Blocks until the order is complete or failed and returns the CompletionData or an OrderFailedError
+func (b *bankid) WaitForCompletion(ctx context.Context, orderRef string, opts ...WaitOption) (*CompletionData, error) {
+	o := waitOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-			if collectResponse.Status == Pending {
-				time.Sleep(2 * time.Second)
-				continue
+	var hintCode HintCode
+	for collectResponse, err := range b.Poll(ctx, orderRef) {
+		if err != nil {
+			return nil, err
+		}
+
+		switch collectResponse.Status {
+		case Complete:
+			return &collectResponse.CompletionData, nil
+		case Failed:
+			return nil, OrderFailedError{OrderRef: orderRef, HintCode: collectResponse.HintCode}
+		case Pending:
+			if collectResponse.HintCode != hintCode {
+				hintCode = collectResponse.HintCode
+
+				if o.progress != nil {
+					o.progress(hintCode)
+				}
 			}
+		default:
+			return nil, fmt.Errorf("unknown status %q for order %s", collectResponse.Status, orderRef)
+		}
+	}
+
+	return nil, fmt.Errorf("order %s is still pending", orderRef)
+}
+
+// A goroutine that checks the /collect endpoint every 2 seconds and returns the response in a channel
+// BankID reference: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
+func (b *bankid) CollectRoutine(ctx context.Context, request CollectRequest, response chan *CollectResponse) {
+	defer close(response)
 
+	for collectResponse, err := range b.Poll(ctx, request.OrderRef) {
+		if err != nil {
+			return
+		}
+
+		// don't block forever when the consumer stopped reading
+		select {
+		case response <- collectResponse:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -0,0 +1,360 @@
+package bankid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a client for a server that answers /collect with the given bodies in order, repeating the last one.
+func newTestServer(t *testing.T, bodies ...string) *bankid {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(calls.Add(1)) - 1
+		if i >= len(bodies) {
+			i = len(bodies) - 1
+		}
+		w.Write([]byte(bodies[i]))
+	}))
+	t.Cleanup(server.Close)
+
+	return &bankid{
+		config:       &RequestConfig{UrlBase: server.URL, Client: server.Client()},
+		pollInterval: time.Millisecond,
+	}
+}
+
+func TestPoll(t *testing.T) {
+	b := newTestServer(t,
+		`{"orderRef":"ref","status":"pending","hintCode":"outstandingTransaction"}`,
+		`{"orderRef":"ref","status":"pending","hintCode":"userSign"}`,
+		`{"orderRef":"ref","status":"complete","completionData":{"user":{"name":"Test"}}}`,
+	)
+
+	var hintCodes []HintCode
+	var last *CollectResponse
+	for collectResponse, err := range b.Poll(context.Background(), "ref") {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		hintCodes = append(hintCodes, collectResponse.HintCode)
+		last = collectResponse
+	}
+
+	if len(hintCodes) != 3 || hintCodes[1] != UserSign {
+		t.Errorf("expected three responses, got %v", hintCodes)
+	}
+
+	if last.Status != Complete || last.CompletionData.User.Name != "Test" {
+		t.Errorf("expected the last response to be complete, got %+v", last)
+	}
+}
+
+func TestPollCancelled(t *testing.T) {
+	b := newTestServer(t, `{"orderRef":"ref","status":"pending","hintCode":"outstandingTransaction"}`)
+	b.pollInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var err error
+	for _, err = range b.Poll(ctx, "ref") {
+		cancel()
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the sequence to end with context.Canceled, got %v", err)
+	}
+}
+
+func TestCollectRoutineStopsWithoutReader(t *testing.T) {
+	b := newTestServer(t, `{"orderRef":"ref","status":"pending","hintCode":"outstandingTransaction"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	response := make(chan *CollectResponse)
+
+	done := make(chan struct{})
+	go func() {
+		b.CollectRoutine(ctx, CollectRequest{OrderRef: "ref"}, response)
+		close(done)
+	}()
+
+	<-response
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("CollectRoutine did not return after ctx was cancelled")
+	}
+}
+
+func TestWaitForCompletion(t *testing.T) {
+	t.Run("complete", func(t *testing.T) {
+		b := newTestServer(t,
+			`{"orderRef":"ref","status":"pending","hintCode":"outstandingTransaction"}`,
+			`{"orderRef":"ref","status":"pending","hintCode":"outstandingTransaction"}`,
+			`{"orderRef":"ref","status":"pending","hintCode":"started"}`,
+			`{"orderRef":"ref","status":"pending","hintCode":"userSign"}`,
+			`{"orderRef":"ref","status":"complete","completionData":{"user":{"personalNumber":"190001010106"}}}`,
+		)
+
+		var progress []HintCode
+		completionData, err := b.WaitForCompletion(context.Background(), "ref", OnProgress(func(h HintCode) {
+			progress = append(progress, h)
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if completionData.User.PersonalNumber != "190001010106" {
+			t.Errorf("unexpected completion data: %+v", completionData)
+		}
+
+		expected := []HintCode{OutstandingTransaction, Started, UserSign}
+		if len(progress) != len(expected) {
+			t.Fatalf("expected progress %v, got %v", expected, progress)
+		}
+		for i := range expected {
+			if progress[i] != expected[i] {
+				t.Errorf("expected progress %v, got %v", expected, progress)
+			}
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		b := newTestServer(t,
+			`{"orderRef":"ref","status":"pending","hintCode":"userSign"}`,
+			`{"orderRef":"ref","status":"failed","hintCode":"userCancel"}`,
+		)
+
+		_, err := b.WaitForCompletion(context.Background(), "ref")
+
+		var failed OrderFailedError
+		if !errors.As(err, &failed) || failed.HintCode != UserCancel {
+			t.Errorf("expected OrderFailedError with userCancel, got %v", err)
+		}
+	})
+}
+
+func TestRiskPolicy(t *testing.T) {
+	b := newTestServer(t, `{"orderRef":"ref","status":"complete","completionData":{"user":{"name":"Test"},"risk":"high"}}`)
+
+	b.config.RiskPolicy = &RiskPolicy{MaxRisk: RiskModerate}
+	collectResponse, err := b.Collect(context.Background(), CollectRequest{OrderRef: "ref"})
+
+	var rejected RiskRejectedError
+	if !errors.As(err, &rejected) || rejected.Risk != RiskHigh {
+		t.Fatalf("expected RiskRejectedError for high risk, got %v", err)
+	}
+
+	if collectResponse != nil {
+		t.Errorf("expected the completion data to be withheld")
+	}
+
+	b.config.RiskPolicy = &RiskPolicy{MaxRisk: RiskHigh}
+	collectResponse, err = b.Collect(context.Background(), CollectRequest{OrderRef: "ref"})
+	if err != nil || collectResponse.CompletionData.Risk != RiskHigh {
+		t.Errorf("expected high risk to be accepted, got %v", err)
+	}
+}
+
+func TestReturnURL(t *testing.T) {
+	b := newTestServer(t, `{"orderRef":"ref"}`)
+
+	for returnURL, valid := range map[string]bool{
+		"":                              true,
+		"https://example.com/done?id=1": true,
+		"http://example.com/done":       false,
+		"/done":                         false,
+		"myapp://done":                  false,
+	} {
+		_, err := b.Auth(context.Background(), AuthRequest{EndUserIP: "192.168.0.1", ReturnURL: returnURL, ReturnRisk: true})
+
+		var invalid InputInvalidError
+		if valid != !errors.As(err, &invalid) {
+			t.Errorf("returnUrl %q: expected valid %t, got %v", returnURL, valid, err)
+		}
+	}
+}
+
+func TestDeviceContext(t *testing.T) {
+	b := newTestServer(t, `{"orderRef":"ref"}`)
+
+	app := &App{AppIdentifier: "com.example.app", DeviceOS: "IOS 17.4", DeviceModelName: "Apple iPhone14,3", DeviceIdentifier: "f1e2d3"}
+	web := &Web{ReferringDomain: "example.com", UserAgent: "Mozilla/5.0", DeviceIdentifier: "a1b2c3"}
+
+	tests := []struct {
+		name  string
+		req   AuthRequest
+		valid bool
+	}{
+		{"none", AuthRequest{}, true},
+		{"app", AuthRequest{App: app}, true},
+		{"web", AuthRequest{Web: web}, true},
+		{"both", AuthRequest{App: app, Web: web}, false},
+		{"incomplete app", AuthRequest{App: &App{AppIdentifier: "com.example.app"}}, false},
+		{"domain with scheme", AuthRequest{Web: &Web{ReferringDomain: "https://example.com", UserAgent: "Mozilla/5.0", DeviceIdentifier: "a1b2c3"}}, false},
+		{"domain with port", AuthRequest{Web: &Web{ReferringDomain: "example.com:8443", UserAgent: "Mozilla/5.0", DeviceIdentifier: "a1b2c3"}}, false},
+		{"ipv6 address", AuthRequest{Web: &Web{ReferringDomain: "::1", UserAgent: "Mozilla/5.0", DeviceIdentifier: "a1b2c3"}}, true},
+	}
+
+	for _, tt := range tests {
+		tt.req.EndUserIP = "192.168.0.1"
+		_, err := b.Auth(context.Background(), tt.req)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: expected valid %t, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestWebFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "https://api.example.com:8443/login", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+
+	web := WebFromRequest(r, "a1b2c3")
+	if web.ReferringDomain != "api.example.com" || web.UserAgent != "Mozilla/5.0" || web.DeviceIdentifier != "a1b2c3" {
+		t.Errorf("unexpected web from host: %+v", web)
+	}
+
+	r.Header.Set("Origin", "https://www.example.com")
+	if web := WebFromRequest(r, "a1b2c3"); web.ReferringDomain != "www.example.com" {
+		t.Errorf("expected the domain of the origin, got %s", web.ReferringDomain)
+	}
+
+	for _, host := range []string{"[::1]", "[::1]:8443"} {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.Host = host
+
+		if web := WebFromRequest(r, "a1b2c3"); web.ReferringDomain != "::1" {
+			t.Errorf("expected ::1 for host %s, got %s", host, web.ReferringDomain)
+		}
+	}
+}
+
+func TestPayment(t *testing.T) {
+	var path string
+	var body PaymentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"orderRef":"ref","autoStartToken":"token","qrStartToken":"qr","qrStartSecret":"secret"}`))
+	}))
+	defer server.Close()
+
+	b := &bankid{config: &RequestConfig{UrlBase: server.URL, Client: server.Client()}}
+
+	paymentResponse, err := b.Payment(context.Background(), PaymentRequest{
+		EndUserIP: "192.168.0.1",
+		UserVisibleTransaction: UserVisibleTransaction{
+			TransactionType: TransactionTypeCard,
+			Recipient:       Recipient{Name: "Example AB"},
+			Money:           &Money{Amount: "1249,50", Currency: "SEK"},
+		},
+		RiskFlags: []RiskFlag{RiskFlagNewCard},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path != "/payment" || paymentResponse.OrderRef != "ref" || paymentResponse.QrStartSecret != "secret" {
+		t.Errorf("unexpected payment to %s: %+v", path, paymentResponse)
+	}
+
+	if body.UserVisibleTransaction.Money.Amount != "1249,50" || body.RiskFlags[0] != RiskFlagNewCard {
+		t.Errorf("unexpected request body: %+v", body)
+	}
+}
+
+func TestPaymentValidation(t *testing.T) {
+	b := newTestServer(t, `{"orderRef":"ref"}`)
+
+	card := func(money *Money) UserVisibleTransaction {
+		return UserVisibleTransaction{TransactionType: TransactionTypeCard, Recipient: Recipient{Name: "Example AB"}, Money: money}
+	}
+
+	tests := []struct {
+		name  string
+		req   PaymentRequest
+		valid bool
+	}{
+		{"card", PaymentRequest{UserVisibleTransaction: card(&Money{Amount: "100", Currency: "SEK"})}, true},
+		{"npa", PaymentRequest{UserVisibleTransaction: UserVisibleTransaction{TransactionType: TransactionTypeNPA, Recipient: Recipient{Name: "Example AB"}}}, true},
+		{"missing transaction", PaymentRequest{}, false},
+		{"card without money", PaymentRequest{UserVisibleTransaction: card(nil)}, false},
+		{"decimal point", PaymentRequest{UserVisibleTransaction: card(&Money{Amount: "100.00", Currency: "SEK"})}, false},
+		{"currency", PaymentRequest{UserVisibleTransaction: card(&Money{Amount: "100", Currency: "kr"})}, false},
+		{"unknown type", PaymentRequest{UserVisibleTransaction: UserVisibleTransaction{TransactionType: "swish", Recipient: Recipient{Name: "Example AB"}}}, false},
+		{"unknown risk flag", PaymentRequest{UserVisibleTransaction: card(&Money{Amount: "100", Currency: "SEK"}), RiskFlags: []RiskFlag{"unknown"}}, false},
+	}
+
+	for _, tt := range tests {
+		tt.req.EndUserIP = "192.168.0.1"
+		_, err := b.Payment(context.Background(), tt.req)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: expected valid %t, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req VerifyRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.QRCode {
+		case "BANKIDF.valid":
+		case "BANKIDF.malformed":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errorCode":"invalidParameters","details":"Invalid request body"}`))
+			return
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errorCode":"invalidParameters","details":"Invalid qrCode"}`))
+			return
+		}
+
+		w.Write([]byte(`{"user":{"personalNumber":"190000000000","name":"Karl Karlsson","age":36},` +
+			`"verification":{"verificationId":"id","verifiedAt":"2024-05-16T09:21:04Z","signature":"c2ln"},` +
+			`"authentication":{"identifiedAt":"2024-05-16T09:20:51Z"}}`))
+	}))
+	defer server.Close()
+
+	b := &bankid{config: &RequestConfig{UrlBase: server.URL, Client: server.Client()}}
+
+	verifyResponse, err := b.Verify(context.Background(), VerifyRequest{QRCode: "BANKIDF.valid"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if verifyResponse.User.Age != 36 || verifyResponse.Verification.VerifiedAt.Minute() != 21 {
+		t.Errorf("unexpected response: %+v", verifyResponse)
+	}
+
+	_, err = b.Verify(context.Background(), VerifyRequest{QRCode: "BANKIDF.expired"})
+
+	var notVerified IDCardNotVerifiedError
+	if !errors.As(err, &notVerified) || notVerified.Details != "Invalid qrCode" || !errors.Is(err, ErrInvalidParameters) {
+		t.Errorf("expected an IDCardNotVerifiedError, got %v", err)
+	}
+
+	// errors of the RP are not reported as an ID card that couldn't be verified
+	_, err = b.Verify(context.Background(), VerifyRequest{QRCode: "BANKIDF.malformed"})
+	if errors.As(err, &notVerified) || !errors.Is(err, ErrInvalidParameters) {
+		t.Errorf("expected a BankIDError for invalid parameters, got %v", err)
+	}
+
+	_, err = b.Verify(context.Background(), VerifyRequest{QRCode: "bankid.token.0.hash"})
+
+	var invalid InputInvalidError
+	if !errors.As(err, &invalid) {
+		t.Errorf("expected the QR code of an order to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package bankid
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Universal link that starts the BankID app on iOS and Android.
+	BankIDAppURL = "https://app.bankid.com/"
+
+	// Custom scheme that starts the BankID Security Application on desktop computers.
+	BankIDAppScheme = "bankid:///"
+)
+
+// Platform of the user's device, the way the BankID app is started differs between platforms.
+type Platform string
+
+const (
+	PlatformUnknown Platform = ""
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+	PlatformDesktop Platform = "desktop"
+)
+
+// RedirectPolicy restricts where the BankID app may return the user to, to avoid open redirects.
+type RedirectPolicy struct {
+	// Optional: Hosts that https redirects may point to, e.g. "example.com" or "*.example.com" for all subdomains.
+	// Default: only the Host of the AutoStartLink
+	AllowedHosts []string `json:"allowedHosts"`
+
+	// Optional: Custom URL schemes of the RP's own apps that may be used as redirect, e.g. "myapp".
+	// Default: only https redirects are allowed
+	AllowedSchemes []string `json:"allowedSchemes"`
+}
+
+// AutoStartLink builds the URL that starts the BankID app on the same device as the RP's service.
+// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/programstart
+//
+// Example:
+//
+//	link, err := bankid.AutoStartLink{
+//		AutoStartToken: authResponse.AutoStartToken,
+//		Redirect:       "https://example.com/login/done",
+//		UserAgent:      r.UserAgent(),
+//		RedirectPolicy: &bankid.RedirectPolicy{AllowedHosts: []string{"example.com"}},
+//	}.URL()
+type AutoStartLink struct {
+	// Required: The autoStartToken returned when the order was started.
+	AutoStartToken string `json:"autoStartToken"`
+
+	// Optional: Where the user returns to after the order, only used on iOS where the BankID app can't return to the previous app by itself.
+	// Use the URL of the current page for browsers, or the URL scheme of the RP's app when the link is opened from an app.
+	Redirect string `json:"redirect,omitempty"`
+
+	// Optional: The platform of the user's device.
+	// Default: detected from UserAgent, or PlatformDesktop if it can't be detected
+	Platform Platform `json:"platform,omitempty"`
+
+	// Optional: The User-Agent header of the user's browser, used to detect the platform and in-app browsers.
+	UserAgent string `json:"userAgent,omitempty"`
+
+	// Optional: The host of the RP's page the link is shown on, e.g. r.Host. Https redirects to it are allowed
+	// when the RedirectPolicy has no AllowedHosts.
+	Host string `json:"host,omitempty"`
+
+	// Optional: Set when the browser reports a touch screen, e.g. navigator.maxTouchPoints > 1.
+	// iPadOS Safari sends the User-Agent of a Mac, the touch screen tells an iPad from a Mac.
+	Touch bool `json:"touch,omitempty"`
+
+	// Optional: Restricts the allowed redirects. Https redirects require AllowedHosts or the Host of the link,
+	// redirects to any other host are rejected to avoid open redirects.
+	// Default: only https redirects to Host are allowed
+	RedirectPolicy *RedirectPolicy `json:"redirectPolicy,omitempty"`
+}
+
+// URL returns the launch URL for the platform:
+//   - iOS: the universal link with the encoded redirect, in-app browsers can't be returned to so they use redirect=null
+//   - Android: the universal link with redirect=null, the BankID app returns to the previous app by itself
+//   - Desktop: the bankid:/// scheme with redirect=null
+func (l AutoStartLink) URL() (string, error) {
+	if l.AutoStartToken == "" {
+		return "", RequiredInputMissingError{Message: "AutoStartToken is missing but required to start the BankID app"}
+	}
+
+	if l.Redirect != "" {
+		err := l.RedirectPolicy.validate(l.Redirect, l.Host)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	platform := l.Platform
+	if platform == PlatformUnknown {
+		platform = DetectPlatform(l.UserAgent)
+
+		if platform == PlatformDesktop && l.Touch && strings.Contains(l.UserAgent, "Macintosh") {
+			platform = PlatformIOS
+		}
+	}
+
+	redirect := "null"
+	if platform == PlatformIOS && l.Redirect != "" && !isInAppBrowser(l.UserAgent) {
+		redirect = url.QueryEscape(l.Redirect)
+	}
+
+	base := BankIDAppURL
+	if platform == PlatformDesktop || platform == PlatformUnknown {
+		base = BankIDAppScheme
+	}
+
+	return fmt.Sprintf("%s?autostarttoken=%s&redirect=%s", base, url.QueryEscape(l.AutoStartToken), redirect), nil
+}
+
+// DetectPlatform detects the platform from a User-Agent header, unknown agents are treated as desktop.
+// iPadOS Safari sends the User-Agent of a Mac and is only detected as iOS by the Mobile/ token of in-app browsers,
+// set AutoStartLink.Touch to detect it in Safari.
+func DetectPlatform(userAgent string) Platform {
+	switch {
+	case userAgent == "":
+		return PlatformUnknown
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return PlatformIOS
+	case strings.Contains(userAgent, "Macintosh") && strings.Contains(userAgent, "Mobile/"):
+		return PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		return PlatformAndroid
+	default:
+		return PlatformDesktop
+	}
+}
+
+// isInAppBrowser reports whether the User-Agent belongs to a browser embedded in another app, e.g. Facebook or Instagram.
+func isInAppBrowser(userAgent string) bool {
+	for _, marker := range []string{"FBAN", "FBAV", "Instagram", "LinkedInApp", "Snapchat", "Line/", "MicroMessenger", "GSA/", "Twitter", "; wv)"} {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate ensures the redirect is an absolute URL with an allowed scheme and host. Without AllowedHosts, only the host
+// of the link's page is allowed, and a nil policy only allows https.
+func (p *RedirectPolicy) validate(redirect string, pageHost string) error {
+	u, err := url.Parse(redirect)
+	if err != nil || u.Scheme == "" {
+		return InputInvalidError{Message: fmt.Sprintf("Redirect: %s is not an absolute URL", redactURL(redirect))}
+	}
+
+	if u.Scheme != "https" {
+		if p == nil || !containsFold(p.AllowedSchemes, u.Scheme) {
+			return InputInvalidError{Message: fmt.Sprintf("Redirect scheme: %s is not allowed", u.Scheme)}
+		}
+
+		return nil
+	}
+
+	if u.Host == "" {
+		return InputInvalidError{Message: fmt.Sprintf("Redirect: %s has no host", redactURL(redirect))}
+	}
+
+	var allowedHosts []string
+	switch {
+	case p != nil && len(p.AllowedHosts) > 0:
+		allowedHosts = p.AllowedHosts
+	case pageHost != "":
+		// the host may have a port, e.g. the Host header "example.com:8443"
+		allowedHosts = []string{(&url.URL{Host: pageHost}).Hostname()}
+	default:
+		return RequiredInputMissingError{Message: "RedirectPolicy.AllowedHosts or Host is missing but required to allow an https redirect"}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return nil
+		}
+
+		if host == allowed {
+			return nil
+		}
+	}
+
+	return InputInvalidError{Message: fmt.Sprintf("Redirect host: %s is not allowed", host)}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
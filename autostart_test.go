@@ -0,0 +1,104 @@
+package bankid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAutoStartLink(t *testing.T) {
+	const (
+		iPhone    = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		instagram = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 326.0.3.30.91"
+		android   = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+		windows   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+		iPadOS    = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15"
+		iPadOSApp = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148"
+		token     = "46f6aa68-9cea-4b2c-8b7e-7d0b5d3ae91a"
+	)
+
+	policy := &RedirectPolicy{AllowedHosts: []string{"*.example.com"}, AllowedSchemes: []string{"myapp"}}
+
+	for _, tt := range []struct {
+		name     string
+		link     AutoStartLink
+		expected string
+	}{
+		{
+			name:     "iOS Safari returns to the page",
+			link:     AutoStartLink{AutoStartToken: token, Redirect: "https://www.example.com/login?state=a&b=c", UserAgent: iPhone, RedirectPolicy: policy},
+			expected: "https://app.bankid.com/?autostarttoken=" + token + "&redirect=https%3A%2F%2Fwww.example.com%2Flogin%3Fstate%3Da%26b%3Dc",
+		},
+		{
+			name:     "iOS app returns to its own scheme",
+			link:     AutoStartLink{AutoStartToken: token, Redirect: "myapp://login", Platform: PlatformIOS, RedirectPolicy: policy},
+			expected: "https://app.bankid.com/?autostarttoken=" + token + "&redirect=myapp%3A%2F%2Flogin",
+		},
+		{
+			name:     "iOS in-app browser",
+			link:     AutoStartLink{AutoStartToken: token, Redirect: "https://www.example.com/login", UserAgent: instagram, RedirectPolicy: policy},
+			expected: "https://app.bankid.com/?autostarttoken=" + token + "&redirect=null",
+		},
+		{
+			name:     "Android",
+			link:     AutoStartLink{AutoStartToken: token, Redirect: "https://www.example.com/login", UserAgent: android, RedirectPolicy: policy},
+			expected: "https://app.bankid.com/?autostarttoken=" + token + "&redirect=null",
+		},
+		{
+			name:     "iOS Safari returns to the host of the page without a policy",
+			link:     AutoStartLink{AutoStartToken: token, Redirect: "https://example.com/login", UserAgent: iPhone, Host: "example.com:8443"},
+			expected: "https://app.bankid.com/?autostarttoken=" + token + "&redirect=https%3A%2F%2Fexample.com%2Flogin",
+		},
+		{
+			name:     "iPadOS Safari with a touch screen",
+			link:     AutoStartLink{AutoStartToken: token, Redirect: "https://example.com/login", UserAgent: iPadOS, Touch: true, Host: "example.com"},
+			expected: "https://app.bankid.com/?autostarttoken=" + token + "&redirect=https%3A%2F%2Fexample.com%2Flogin",
+		},
+		{
+			name:     "iPadOS in-app browser",
+			link:     AutoStartLink{AutoStartToken: token, UserAgent: iPadOSApp},
+			expected: "https://app.bankid.com/?autostarttoken=" + token + "&redirect=null",
+		},
+		{
+			name:     "Mac",
+			link:     AutoStartLink{AutoStartToken: token, UserAgent: iPadOS},
+			expected: "bankid:///?autostarttoken=" + token + "&redirect=null",
+		},
+		{
+			name:     "Desktop",
+			link:     AutoStartLink{AutoStartToken: token, UserAgent: windows},
+			expected: "bankid:///?autostarttoken=" + token + "&redirect=null",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := tt.link.URL()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if u != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, u)
+			}
+		})
+	}
+
+	// https redirects need an allow-list or the host of the page
+	for _, link := range []AutoStartLink{
+		{AutoStartToken: token, Redirect: "https://evil.com/login", Platform: PlatformIOS},
+		{AutoStartToken: token, Redirect: "https://evil.com/login", Platform: PlatformIOS, RedirectPolicy: &RedirectPolicy{AllowedSchemes: []string{"myapp"}}},
+		{AutoStartToken: token, Redirect: "https://evil.com/login", Platform: PlatformIOS, Host: "example.com"},
+	} {
+		_, err := link.URL()
+		if err == nil {
+			t.Errorf("expected redirect %s to be rejected for host %q and policy %+v", link.Redirect, link.Host, link.RedirectPolicy)
+		}
+	}
+
+	for _, redirect := range []string{"https://evil.com/login", "http://www.example.com", "otherapp://login", "/relative"} {
+		_, err := AutoStartLink{AutoStartToken: token, Redirect: redirect, Platform: PlatformIOS, RedirectPolicy: policy}.URL()
+
+		var invalid InputInvalidError
+		if !errors.As(err, &invalid) {
+			t.Errorf("expected redirect %s to be rejected, got %v", redirect, err)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package bankid
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Canonical XML 1.0 without comments, the canonicalization BankID uses for the signed parts of signatures.
+// Specification: https://www.w3.org/TR/2001/REC-xml-c14n-20010315
+const c14nAlgorithm = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+
+// c14nFrame is an element of the document that is being canonicalized.
+type c14nFrame struct {
+	name string
+
+	// the namespaces in scope of the element, by prefix, the default namespace has the prefix ""
+	namespaces map[string]string
+
+	// the xml:* attributes in scope of the element, e.g. xml:lang
+	xmlAttrs map[string]string
+
+	// the namespaces as rendered in the output for the element, only set inside the canonicalized element
+	rendered map[string]string
+}
+
+// canonicalElement returns the canonical form of the only element of the document at path, e.g. "Signature", "SignedInfo",
+// together with the value of its Id attribute. It fails if there isn't exactly one element at path.
+func canonicalElement(doc []byte, path ...string) ([]byte, string, error) {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+
+	var (
+		stack   []c14nFrame
+		out     bytes.Buffer
+		id      string
+		matches int
+
+		// the depth of the canonicalized element in stack, -1 outside of it
+		apex = -1
+	)
+
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading xml: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			parent := c14nFrame{namespaces: map[string]string{}, xmlAttrs: map[string]string{}}
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			frame := c14nFrame{
+				name:       qualifiedName(t.Name),
+				namespaces: parent.namespaces,
+				xmlAttrs:   parent.xmlAttrs,
+			}
+
+			var attrs []xml.Attr
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					frame.namespaces = with(frame.namespaces, "", a.Value)
+				case a.Name.Space == "xmlns":
+					frame.namespaces = with(frame.namespaces, a.Name.Local, a.Value)
+				case a.Name.Space == "xml":
+					frame.xmlAttrs = with(frame.xmlAttrs, a.Name.Local, a.Value)
+					attrs = append(attrs, a)
+				default:
+					attrs = append(attrs, a)
+				}
+			}
+
+			stack = append(stack, frame)
+
+			if apex < 0 && matchesPath(stack, path) {
+				matches++
+				if matches > 1 {
+					return nil, "", fmt.Errorf("more than one element at %s", strings.Join(path, "/"))
+				}
+
+				apex = len(stack) - 1
+				id = attrValue(t.Attr, "Id")
+
+				// the canonical form of a subtree carries the xml:* attributes it inherits from its ancestors
+				for local, value := range parent.xmlAttrs {
+					if attrValue(attrs, "xml:"+local) == "" {
+						attrs = append(attrs, xml.Attr{Name: xml.Name{Space: "xml", Local: local}, Value: value})
+					}
+				}
+			}
+
+			if apex >= 0 {
+				// the canonicalized element has no rendered ancestors
+				parentRendered := map[string]string{}
+				if len(stack)-1 > apex {
+					parentRendered = stack[len(stack)-2].rendered
+				}
+
+				stack[len(stack)-1].rendered = writeStart(&out, frame, parentRendered, attrs)
+			}
+
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].name != qualifiedName(t.Name) {
+				return nil, "", fmt.Errorf("unexpected end element %s", qualifiedName(t.Name))
+			}
+
+			if apex >= 0 {
+				fmt.Fprintf(&out, "</%s>", stack[len(stack)-1].name)
+				if apex == len(stack)-1 {
+					apex = -1
+				}
+			}
+
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			if apex >= 0 {
+				out.WriteString(escapeText(string(t)))
+			}
+
+		case xml.ProcInst:
+			if apex >= 0 {
+				fmt.Fprintf(&out, "<?%s", t.Target)
+				if len(t.Inst) > 0 {
+					fmt.Fprintf(&out, " %s", t.Inst)
+				}
+				out.WriteString("?>")
+			}
+
+		case xml.Comment, xml.Directive:
+			// comments are removed and document type declarations are outside of elements
+		}
+	}
+
+	if matches == 0 {
+		return nil, "", fmt.Errorf("no element at %s", strings.Join(path, "/"))
+	}
+
+	return out.Bytes(), id, nil
+}
+
+// writeStart writes the canonical start tag of an element and returns the namespaces rendered for it.
+// Namespace declarations are written when they differ from the ones rendered for the closest canonicalized ancestor,
+// sorted by prefix and followed by the attributes sorted by namespace URI and local name.
+func writeStart(out *bytes.Buffer, frame c14nFrame, parentRendered map[string]string, attrs []xml.Attr) map[string]string {
+	rendered := make(map[string]string, len(frame.namespaces))
+	for prefix, uri := range parentRendered {
+		rendered[prefix] = uri
+	}
+
+	var prefixes []string
+	for prefix, uri := range frame.namespaces {
+		if prefix == "xml" {
+			continue
+		}
+
+		previous, ok := parentRendered[prefix]
+		if ok && previous == uri {
+			continue
+		}
+
+		// an empty default namespace is only declared to undo a default namespace of an ancestor
+		if prefix == "" && uri == "" && !ok {
+			continue
+		}
+
+		prefixes = append(prefixes, prefix)
+		rendered[prefix] = uri
+	}
+	slices.Sort(prefixes)
+
+	namespaceOf := func(a xml.Attr) string {
+		switch a.Name.Space {
+		case "":
+			return ""
+		case "xml":
+			return "http://www.w3.org/XML/1998/namespace"
+		default:
+			return frame.namespaces[a.Name.Space]
+		}
+	}
+
+	slices.SortFunc(attrs, func(a, b xml.Attr) int {
+		if c := strings.Compare(namespaceOf(a), namespaceOf(b)); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Name.Local, b.Name.Local)
+	})
+
+	fmt.Fprintf(out, "<%s", frame.name)
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			fmt.Fprintf(out, ` xmlns="%s"`, escapeAttr(frame.namespaces[prefix]))
+		} else {
+			fmt.Fprintf(out, ` xmlns:%s="%s"`, prefix, escapeAttr(frame.namespaces[prefix]))
+		}
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(out, ` %s="%s"`, qualifiedName(a.Name), escapeAttr(a.Value))
+	}
+	out.WriteString(">")
+
+	return rendered
+}
+
+func matchesPath(stack []c14nFrame, path []string) bool {
+	if len(stack) != len(path) {
+		return false
+	}
+
+	for i, frame := range stack {
+		if localName(frame.name) != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+
+	return n.Space + ":" + n.Local
+}
+
+func localName(name string) string {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[i+1:]
+	}
+
+	return name
+}
+
+func attrValue(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if qualifiedName(a.Name) == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// with returns a copy of m with the key set, the maps of ancestors are shared and never changed.
+func with(m map[string]string, key string, value string) map[string]string {
+	c := make(map[string]string, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	c[key] = value
+
+	return c
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
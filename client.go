@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"time"
+	"unicode/utf8"
 
 	"github.com/youmark/pkcs8"
 	"software.sslmate.com/src/go-pkcs12"
@@ -22,8 +23,13 @@ import (
 //   - All methods are accessed using HTTP POST.
 //   - HTTP header 'Content-Type' must be set to 'application/json'.
 type RequestConfig struct {
-	UrlBase string
-	Client  *http.Client
+	UrlBase    string
+	Client     *http.Client
+	Retry      *RetryPolicy
+	RiskPolicy *RiskPolicy
+
+	// The API version of UrlBase, requests are checked against its schema. Requests are not checked if it's empty.
+	Version APIVersion
 }
 
 type RequestParameters struct {
@@ -33,12 +39,31 @@ type RequestParameters struct {
 }
 
 // request sends a request to the BankID API and handles and returns the response or error.
-func request[T ResponseBody](ctx context.Context, p RequestParameters) (r *T, err error) {
+// The request is retried according to the retry policy of the config, if any.
+func request[T ResponseBody](ctx context.Context, p RequestParameters) (*T, error) {
 	b, err := p.Body.Marshal()
 	if err != nil {
 		return nil, fmt.Errorf("error marshalling body: %w", err)
 	}
 
+	if p.Config.Version != "" {
+		err = p.Config.Version.check(p.Path, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Config.Retry == nil {
+		return send[T](ctx, p, b)
+	}
+
+	return retry(ctx, *p.Config.Retry, func() (*T, error) {
+		return send[T](ctx, p, b)
+	})
+}
+
+// send makes a single attempt to send the marshalled body of a request to the BankID API.
+func send[T ResponseBody](ctx context.Context, p RequestParameters, b []byte) (r *T, err error) {
 	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s%s", p.Config.UrlBase, p.Path), bytes.NewBuffer(b))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -48,23 +73,24 @@ func request[T ResponseBody](ctx context.Context, p RequestParameters) (r *T, er
 
 	res, err := p.Config.Client.Do(req)
 	if res == nil {
-		return nil, fmt.Errorf("error request: %w", err)
+		return nil, TransportError{Path: p.Path, OrderRef: orderRefOf(p.Body), Err: err}
 	}
 	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		return nil, TransportError{Path: p.Path, OrderRef: orderRefOf(p.Body), Err: fmt.Errorf("error reading response body: %w", err)}
 	}
 
 	if res.StatusCode >= 300 {
-		e := BankIDError{}
+		e := ErrorResponseBody{}
 		err := json.Unmarshal(body, &e)
 		if err != nil {
-			return nil, fmt.Errorf("unknown error: %w", err)
+			// keep what was received, e.g. an HTML error page from a proxy in front of BankID
+			e = ErrorResponseBody{ErrorCode: UnknownErrorCode, Details: truncate(string(body), 512)}
 		}
 
-		return nil, assignError(e.ErrorCode)
+		return nil, assignError(e, res.StatusCode, p.Path, orderRefOf(p.Body))
 	}
 
 	err = json.Unmarshal(body, &r)
@@ -75,7 +101,44 @@ func request[T ResponseBody](ctx context.Context, p RequestParameters) (r *T, er
 	return r, nil
 }
 
+// orderRefOf returns the orderRef of requests that refer to an existing order.
+func orderRefOf(body RequestBody) string {
+	switch v := body.(type) {
+	case CollectRequest:
+		return v.OrderRef
+	case CancelRequest:
+		return v.OrderRef
+	default:
+		return ""
+	}
+}
+
+// truncate cuts s to at most n bytes, at the start of a rune so that multi-byte characters aren't split.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n] + "..."
+}
+
 func newRequestConfig(params Config) (*RequestConfig, error) {
+	if params.RiskPolicy != nil {
+		err := params.RiskPolicy.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid risk policy: %w", err)
+		}
+	}
+
+	urlBase, version, err := resolveEndpoint(params.URL, params.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
 	var cert *tls.Certificate
 
 	switch v := params.Certificate.(type) {
@@ -99,25 +162,55 @@ func newRequestConfig(params Config) (*RequestConfig, error) {
 		return nil, fmt.Errorf("could not append root certificate to pool")
 	}
 
-	// Create a new TLS configuration with the key and certificate
-	tlsConfig := &tls.Config{
-		RootCAs: certPool,
-		Certificates: []tls.Certificate{
-			*cert,
-		},
+	// Start from the provided transport, or an empty one, so proxies, dialers and pool limits are kept
+	transport := &http.Transport{}
+	if params.Transport != nil {
+		transport = params.Transport.Clone()
+	}
+
+	tlsConfig := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+
+	if params.ConfigureTLS != nil {
+		params.ConfigureTLS(tlsConfig)
+	}
+
+	// The key, certificate and CA are always applied on top of any customisation
+	tlsConfig.RootCAs = certPool
+	tlsConfig.Certificates = []tls.Certificate{
+		*cert,
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// BankID requires HTTP1.1, an empty non-nil TLSNextProto disables HTTP/2
+	transport.ForceAttemptHTTP2 = false
+	transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+
+	var roundTripper http.RoundTripper = transport
+	if params.WrapTransport != nil {
+		roundTripper = params.WrapTransport(transport)
 	}
 
 	// Create an HTTP client with the custom TLS configuration
 	client := &http.Client{
-		Timeout: time.Second * time.Duration(params.Timeout),
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
-		},
+		Timeout:   time.Second * time.Duration(params.Timeout),
+		Transport: roundTripper,
+	}
+
+	var retryPolicy *RetryPolicy
+	if params.Retry != nil {
+		p := params.Retry.UseDefault()
+		retryPolicy = &p
 	}
 
 	return &RequestConfig{
-		UrlBase: params.URL,
-		Client:  client,
+		UrlBase:    urlBase,
+		Client:     client,
+		Retry:      retryPolicy,
+		RiskPolicy: params.RiskPolicy,
+		Version:    version,
 	}, nil
 }
 
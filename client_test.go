@@ -0,0 +1,126 @@
+package bankid
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type wrappedTransport struct {
+	next http.RoundTripper
+}
+
+func (w wrappedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return w.next.RoundTrip(r)
+}
+
+func TestRequestConfigTransport(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.internal:3128")
+
+	c, err := newRequestConfig(Config{
+		URL: BankIDTestUrl,
+		Certificate: P12Cert{
+			Passphrase:    BankIDTestPassphrase,
+			Certificate:   P12TestCertificate,
+			CACertificate: CATestCertificate,
+		},
+		Transport: &http.Transport{
+			Proxy:               http.ProxyURL(proxy),
+			MaxIdleConnsPerHost: 7,
+		},
+		ConfigureTLS: func(c *tls.Config) {
+			c.MinVersion = tls.VersionTLS13
+		},
+		WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+			return wrappedTransport{next: rt}
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wrapped, ok := c.Client.Transport.(wrappedTransport)
+	if !ok {
+		t.Fatalf("expected wrapped transport, got %T", c.Client.Transport)
+	}
+
+	transport, ok := wrapped.next.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected *http.Transport, got %T", wrapped.next)
+	}
+
+	if transport.MaxIdleConnsPerHost != 7 || transport.Proxy == nil {
+		t.Errorf("base transport settings were not kept")
+	}
+
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected TLS customisation to be kept, got min version %x", transport.TLSClientConfig.MinVersion)
+	}
+
+	if len(transport.TLSClientConfig.Certificates) != 1 || transport.TLSClientConfig.RootCAs == nil {
+		t.Errorf("expected RP certificate and BankID CA to be set")
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"errorCode":"internalError","details":"Backend unavailable, reference 42"}`))
+	}))
+	defer server.Close()
+
+	_, err := request[CollectResponse](context.Background(), RequestParameters{
+		Path:   "/collect",
+		Config: &RequestConfig{UrlBase: server.URL, Client: server.Client()},
+		Body:   CollectRequest{OrderRef: "order-1"},
+	})
+
+	if !errors.Is(err, ErrInternalError) {
+		t.Fatalf("expected error to match ErrInternalError, got %v", err)
+	}
+
+	var e BankIDError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected BankIDError, got %T", err)
+	}
+
+	if e.Details != "Backend unavailable, reference 42" || e.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected details and status code from BankID, got %q and %d", e.Details, e.StatusCode)
+	}
+
+	if e.Path != "/collect" || e.OrderRef != "order-1" {
+		t.Errorf("expected path and orderRef to be set, got %q and %q", e.Path, e.OrderRef)
+	}
+
+	if !e.Temporary() || e.Retryable() {
+		t.Errorf("expected internal error on /collect to be temporary but not retried")
+	}
+
+	if errors.Is(err, ErrMaintenance) || errors.Is(err, ErrUnknownErrorCode) {
+		t.Errorf("expected error to only match ErrInternalError")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	// a proxy error page in Swedish, "å" is two bytes and the limit falls in the middle of one
+	s := strings.Repeat("å", 300)
+
+	truncated := truncate(s, 511)
+	if !utf8.ValidString(truncated) {
+		t.Errorf("expected valid UTF-8, got %q", truncated)
+	}
+
+	if truncated != strings.Repeat("å", 255)+"..." {
+		t.Errorf("expected 255 runes, got %d", utf8.RuneCountInString(strings.TrimSuffix(truncated, "...")))
+	}
+
+	if truncate("ok", 512) != "ok" {
+		t.Errorf("expected a short string to be unchanged")
+	}
+}
@@ -0,0 +1,125 @@
+package bankid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+)
+
+// The signed fields of a request that must be found in the signature of the completed order.
+type signedFields struct {
+	signatureType      SignatureType
+	userVisibleData    string
+	userNonVisibleData string
+	personalNumber     string
+}
+
+// CheckCompletion checks that the signature of a completed order contains exactly what the request asked the user to sign.
+//   - The visible and non-visible data equal the data of the request after it's processed like Auth, Sign, Phone and Payment do.
+//   - The signature was created by the kind of order of the request, an auth or a sign order.
+//   - The personal number of the user equals the personal number of the request or its Requirement, when one was given.
+//
+// The signature is only parsed, use a SignatureVerifier to verify it. A mismatch is returned as a CompletionMismatchError.
+//
+// Example:
+//
+//	signature, err := bankid.CheckCompletion(signRequest, *collectResponse)
+//	var mismatch bankid.CompletionMismatchError
+//	if errors.As(err, &mismatch) {
+//		// the user didn't sign what was requested
+//	}
+func CheckCompletion[T RequestBody](request T, response CollectResponse) (*BankIDSignature, error) {
+	if response.Status != Complete {
+		return nil, InputInvalidError{Message: fmt.Sprintf("order %s is not complete, status: %s", response.OrderRef, response.Status)}
+	}
+
+	expected, err := signedFieldsOf(request)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := ParseSignature(response.CompletionData.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatch := func(field string) error {
+		return CompletionMismatchError{OrderRef: response.OrderRef, Field: field}
+	}
+
+	if expected.signatureType != "" && s.Type != expected.signatureType {
+		return nil, mismatch("type")
+	}
+
+	userVisibleData, err := base64.StdEncoding.DecodeString(expected.userVisibleData)
+	if err != nil || !bytes.Equal(userVisibleData, []byte(s.UserVisibleData)) {
+		return nil, mismatch("userVisibleData")
+	}
+
+	userNonVisibleData, err := base64.StdEncoding.DecodeString(expected.userNonVisibleData)
+	if err != nil || !bytes.Equal(userNonVisibleData, s.UserNonVisibleData) {
+		return nil, mismatch("userNonVisibleData")
+	}
+
+	if expected.personalNumber != "" {
+		if response.CompletionData.User.PersonalNumber != expected.personalNumber {
+			return nil, mismatch("personalNumber")
+		}
+
+		// the serial number of the subject of the user's certificate is the personal number
+		cert := s.UserCertificate()
+		if cert != nil && cert.Subject.SerialNumber != "" && cert.Subject.SerialNumber != expected.personalNumber {
+			return nil, mismatch("personalNumber")
+		}
+	}
+
+	return s, nil
+}
+
+// signedFieldsOf processes the request like the order was started and returns its signed fields.
+func signedFieldsOf(rb RequestBody) (signedFields, error) {
+	var f signedFields
+	var requirement *Requirement
+
+	switch v := rb.(type) {
+	case AuthRequest:
+		v, _ = process[AuthRequest](v, processUserVisibleData(v.UserVisibleData), processUserNonVisibleData(v.UserNonVisibleData))
+		f = signedFields{SignatureTypeAuth, v.UserVisibleData, v.UserNonVisibleData, ""}
+		requirement = v.Requirement
+
+	case SignRequest:
+		v, _ = process[SignRequest](v, processUserVisibleData(v.UserVisibleData), processUserNonVisibleData(v.UserNonVisibleData))
+		f = signedFields{SignatureTypeSign, v.UserVisibleData, v.UserNonVisibleData, ""}
+		requirement = v.Requirement
+
+	case PhoneAuthRequest:
+		v, _ = process[PhoneAuthRequest](v, processUserVisibleData(v.UserVisibleData), processUserNonVisibleData(v.UserNonVisibleData))
+		f = signedFields{SignatureTypeAuth, v.UserVisibleData, v.UserNonVisibleData, v.PersonalNumber}
+		requirement = v.Requirement
+
+	case PhoneSignRequest:
+		v, _ = process[PhoneSignRequest](v, processUserVisibleData(v.UserVisibleData), processUserNonVisibleData(v.UserNonVisibleData))
+		f = signedFields{SignatureTypeSign, v.UserVisibleData, v.UserNonVisibleData, v.PersonalNumber}
+		requirement = v.Requirement
+
+	case PaymentRequest:
+		// the signature of a payment order is checked for its data, not for the kind of order
+		v, _ = process[PaymentRequest](v, processUserVisibleData(v.UserVisibleData), processUserNonVisibleData(v.UserNonVisibleData))
+		f = signedFields{"", v.UserVisibleData, v.UserNonVisibleData, ""}
+		requirement = v.Requirement
+
+	default:
+		return f, InputInvalidError{Message: fmt.Sprintf("request %T doesn't create an order with a signature", rb)}
+	}
+
+	if f.personalNumber == "" && requirement != nil {
+		f.personalNumber = requirement.PersonalNumber
+	}
+
+	// BankID returns the personal number in the 12 digit format the request was normalised to
+	if p, err := ParsePersonnummer(f.personalNumber); err == nil {
+		f.personalNumber = p.String()
+	}
+
+	return f, nil
+}
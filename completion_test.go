@@ -0,0 +1,71 @@
+package bankid
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCheckCompletion(t *testing.T) {
+	pki := newTestPKI(t)
+
+	completed := func(funcID string, userVisibleData string, userNonVisibleData []byte, personalNumber string) CollectResponse {
+		return CollectResponse{
+			OrderRef: "131daac9-16c6-4618-beb0-365768f37288",
+			Status:   Complete,
+			CompletionData: CompletionData{
+				User:      User{PersonalNumber: personalNumber},
+				Signature: base64.StdEncoding.EncodeToString([]byte(pki.signatureXML(funcID, userVisibleData, userNonVisibleData))),
+			},
+		}
+	}
+
+	// the request is processed like Sign does, plain text is base64 encoded
+	request := SignRequest{
+		EndUserIP:          "192.168.1.1",
+		UserVisibleData:    "Jag godkänner avtalet",
+		UserNonVisibleData: base64.StdEncoding.EncodeToString([]byte{0x00, 0xff}),
+		Requirement:        &Requirement{PersonalNumber: "199510221287"},
+	}
+
+	s, err := CheckCompletion(request, completed("Signing", "Jag godkänner avtalet", []byte{0x00, 0xff}, "199510221287"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.UserVisibleData != "Jag godkänner avtalet" {
+		t.Errorf("expected the parsed signature, got %q", s.UserVisibleData)
+	}
+
+	_, err = CheckCompletion(AuthRequest{EndUserIP: "192.168.1.1"}, completed("Identification", "", nil, "199510221287"))
+	if err != nil {
+		t.Errorf("unexpected error for an auth order without data: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		request   RequestBody
+		response  CollectResponse
+		wantField string
+	}{
+		{"visible data", request, completed("Signing", "Jag godkänner inte avtalet", []byte{0x00, 0xff}, "199510221287"), "userVisibleData"},
+		{"non-visible data", request, completed("Signing", "Jag godkänner avtalet", []byte{0x00}, "199510221287"), "userNonVisibleData"},
+		{"auth signature", request, completed("Identification", "Jag godkänner avtalet", []byte{0x00, 0xff}, "199510221287"), "type"},
+		{"other user", request, completed("Signing", "Jag godkänner avtalet", []byte{0x00, 0xff}, "198112289874"), "personalNumber"},
+		{"phone order of another user", PhoneAuthRequest{PersonalNumber: "198112289874", CallInitiator: "RP"}, completed("Identification", "", nil, "198112289874"), "personalNumber"},
+	}
+
+	for _, tt := range tests {
+		var mismatch CompletionMismatchError
+
+		_, err := CheckCompletion(tt.request, tt.response)
+		if !errors.As(err, &mismatch) || mismatch.Field != tt.wantField {
+			t.Errorf("%s: expected a mismatch of %s, got %v", tt.name, tt.wantField, err)
+		}
+	}
+
+	_, err = CheckCompletion(request, CollectResponse{Status: Pending})
+	if err == nil {
+		t.Errorf("expected an error for a pending order")
+	}
+}
@@ -1,16 +1,50 @@
 package bankid
 
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
 type Config struct {
 	// Required: The SSL & CA certificate for the client.
 	Certificate
 
 	// Optional: The URL to BankID API, can be set to the test or production endpoint.
+	// A URL without a base path, e.g. BankIDTestHost, gets the path of Version.
 	// Default: "https://appapi2.bankid.com/rp/v6.0"
 	URL string `json:"url"`
 
+	// Optional: The version of the BankID API. Requests with fields or to endpoints the version doesn't support
+	// fail with an UnsupportedFieldError or UnsupportedEndpointError before they are sent.
+	// Only requests are versioned, responses are decoded into the same types for every version.
+	// Default: the version in the path of URL, or DefaultAPIVersion
+	Version APIVersion `json:"version,omitempty"`
+
 	// Optional: The timeout for the request to BankID API in seconds.
 	// Default: 5
 	Timeout int `json:"timeout"`
+
+	// Optional: The policy used to retry requests that failed with a transient error.
+	// Default: requests are not retried
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// Optional: Rejects completed orders above a risk level before they are returned by Collect.
+	// Default: completed orders are returned regardless of their risk
+	RiskPolicy *RiskPolicy `json:"riskPolicy,omitempty"`
+
+	// Optional: A base transport used for requests to the BankID API, e.g. to set an egress proxy, a custom dialer or connection pool limits.
+	// The transport is cloned, the RP certificate and the BankID CA are added to the TLS configuration of the clone.
+	// Default: an empty http.Transport
+	Transport *http.Transport `json:"-"`
+
+	// Optional: Customises the TLS configuration (min version, cipher suites, session cache, ...) before it's used.
+	// The RP certificate and the BankID CA are always set on top of the changes made by the callback.
+	ConfigureTLS func(*tls.Config) `json:"-"`
+
+	// Optional: Wraps the transport that carries the RP certificate, e.g. to route every call through an instrumented round tripper.
+	WrapTransport func(http.RoundTripper) http.RoundTripper `json:"-"`
 }
 
 // Ensures input data is set based on BankID requirements or leaves the input unchanged if it's valid or optional
@@ -18,6 +52,9 @@ func (c *Config) UseDefault() {
 	if c.URL == "" {
 		// Set the URL to the default production endpoint if not provided
 		c.URL = BankIDURL
+		if c.Version != "" {
+			c.URL = c.Version.URL(BankIDHost)
+		}
 	}
 
 	// Set the timeout to 5 seconds if not provided
@@ -27,19 +64,58 @@ func (c *Config) UseDefault() {
 
 	// Use the BankID CA root certificate for dev and prod scnearios by default for requests
 	if c.CA() == nil {
+		var ca []byte
+		if strings.HasPrefix(c.URL, BankIDHost) {
+			ca = CAProdCertificate
+		} else if strings.HasPrefix(c.URL, BankIDTestHost) {
+			ca = CATestCertificate
+		}
+
 		switch v := c.Certificate.(type) {
 		case P12Cert:
-			if c.URL == BankIDURL {
-				v.CACertificate = CAProdCertificate
-			} else if c.URL == BankIDTestUrl {
-				v.CACertificate = CATestCertificate
-			}
+			v.CACertificate = ca
+			c.Certificate = v
 		case PEMCert:
-			if c.URL == BankIDURL {
-				v.CACertificate = CAProdCertificate
-			} else if c.URL == BankIDTestUrl {
-				v.CACertificate = CATestCertificate
-			}
+			v.CACertificate = ca
+			c.Certificate = v
 		}
 	}
 }
+
+// RiskPolicy rejects completed orders based on the risk indication returned by BankID.
+// Orders must be started with returnRisk set to true for BankID to return a risk indication.
+type RiskPolicy struct {
+	// Required: Completed orders with a higher risk are rejected with a RiskRejectedError.
+	MaxRisk RiskLevel `json:"maxRisk"`
+
+	// Optional: Also reject completed orders without a risk indication.
+	RejectMissing bool `json:"rejectMissing"`
+}
+
+// Validate ensures MaxRisk is a known risk level
+func (p RiskPolicy) Validate() error {
+	switch p.MaxRisk {
+	case RiskLow, RiskModerate, RiskHigh:
+		return nil
+	default:
+		return InputInvalidError{Message: fmt.Sprintf("MaxRisk: %s is invalid, it should be 'low', 'moderate' or 'high'", p.MaxRisk)}
+	}
+}
+
+// check returns a RiskRejectedError when the completed order is not accepted by the policy
+func (p RiskPolicy) check(r *CollectResponse) error {
+	if r.Status != Complete {
+		return nil
+	}
+
+	risk := r.CompletionData.Risk
+	if risk == "" && !p.RejectMissing {
+		return nil
+	}
+
+	if risk == "" || risk.Exceeds(p.MaxRisk) {
+		return RiskRejectedError{OrderRef: r.OrderRef, Risk: risk, MaxRisk: p.MaxRisk}
+	}
+
+	return nil
+}
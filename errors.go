@@ -1,7 +1,10 @@
 package bankid
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 )
 
 // RequiredInputMissingError is an error returned when a required input is missing.
@@ -15,24 +18,360 @@ type InputInvalidError struct {
 }
 
 func (r RequiredInputMissingError) Error() string {
-	return fmt.Sprintf("required input is missing: %s", r.Message)
+	return fmt.Sprintf("required input is missing: %s", redactText(r.Message))
 }
 
 func (r InputInvalidError) Error() string {
-	return fmt.Sprintf("invalid input: %s", r.Message)
+	return fmt.Sprintf("invalid input: %s", redactText(r.Message))
+}
+
+// Is matches every InputInvalidError, so errors.Is(err, InputInvalidError{}) finds invalid input of any message.
+func (r InputInvalidError) Is(target error) bool {
+	_, ok := target.(InputInvalidError)
+	return ok
+}
+
+// PersonnummerError is returned when a personal number isn't a valid personnummer.
+type PersonnummerError struct {
+	Reason string
+}
+
+func (r PersonnummerError) Error() string {
+	return fmt.Sprintf("invalid personnummer: %s", r.Reason)
+}
+
+// Unwrap matches the error as an InputInvalidError with errors.As.
+func (r PersonnummerError) Unwrap() error {
+	return InputInvalidError{Message: fmt.Sprintf("personnummer: %s", r.Reason)}
+}
+
+// SamordningsnummerError is returned when a coordination number, a personal number with the day of birth plus 60, is invalid.
+type SamordningsnummerError struct {
+	Reason string
+}
+
+func (r SamordningsnummerError) Error() string {
+	return fmt.Sprintf("invalid samordningsnummer: %s", r.Reason)
+}
+
+// Unwrap matches the error as an InputInvalidError with errors.As.
+func (r SamordningsnummerError) Unwrap() error {
+	return InputInvalidError{Message: fmt.Sprintf("samordningsnummer: %s", r.Reason)}
+}
+
+// OrganisationsnummerError is returned when an organisationsnummer is invalid, or is used where BankID expects the personal number of a user.
+type OrganisationsnummerError struct {
+	Reason string
+}
+
+func (r OrganisationsnummerError) Error() string {
+	return fmt.Sprintf("invalid organisationsnummer: %s", r.Reason)
+}
+
+// Unwrap matches the error as an InputInvalidError with errors.As.
+func (r OrganisationsnummerError) Unwrap() error {
+	return InputInvalidError{Message: fmt.Sprintf("organisationsnummer: %s", r.Reason)}
+}
+
+// ReservnummerError is returned when a reserve number of the healthcare regions is used as a personal number.
+// Reserve numbers are only valid within the region that issued them, BankID doesn't know them.
+type ReservnummerError struct{}
+
+func (r ReservnummerError) Error() string {
+	return "reservnummer is not supported: BankID identifies users by personnummer or samordningsnummer"
+}
+
+// Unwrap matches the error as an InputInvalidError with errors.As.
+func (r ReservnummerError) Unwrap() error {
+	return InputInvalidError{Message: "reservnummer is not supported"}
+}
+
+// RetryError is returned when a request was sent more than once, it wraps the error of the last attempt.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (r RetryError) Error() string {
+	return fmt.Sprintf("request failed after %d attempts: %v", r.Attempts, r.Err)
+}
+
+func (r RetryError) Unwrap() error {
+	return r.Err
+}
+
+// OrderFailedError is returned when an order ends with status `failed`, the HintCode describes why it failed.
+type OrderFailedError struct {
+	OrderRef string
+	HintCode HintCode
+}
+
+func (r OrderFailedError) Error() string {
+	return fmt.Sprintf("order %s failed: %s", r.OrderRef, r.HintCode)
+}
+
+// RiskRejectedError is returned by Collect when an order completed with a risk above the RiskPolicy of the config.
+// The completion data is withheld from the caller.
+type RiskRejectedError struct {
+	OrderRef string
+	Risk     RiskLevel
+	MaxRisk  RiskLevel
+}
+
+func (r RiskRejectedError) Error() string {
+	risk := r.Risk
+	if risk == "" {
+		risk = "unknown"
+	}
+
+	return fmt.Sprintf("order %s completed with %s risk, the maximum accepted risk is %s", r.OrderRef, risk, r.MaxRisk)
+}
+
+// IDCardNotVerifiedError is returned by Verify when BankID couldn't verify the QR code of a digital ID card,
+// e.g. because the QR code expired or wasn't created by the BankID app. The holder should show a new QR code.
+type IDCardNotVerifiedError struct {
+	Details string
+	Err     error
+}
+
+func (r IDCardNotVerifiedError) Error() string {
+	return fmt.Sprintf("digital ID card could not be verified: %s", r.Details)
+}
+
+func (r IDCardNotVerifiedError) Unwrap() error {
+	return r.Err
+}
+
+// OrderNotTrackedError is returned by Poller.Subscribe for an order the poller doesn't track, e.g. because it was already removed.
+type OrderNotTrackedError struct {
+	OrderRef string
+}
+
+func (r OrderNotTrackedError) Error() string {
+	return fmt.Sprintf("order %s is not tracked by the poller", r.OrderRef)
+}
+
+// UnsupportedEndpointError is returned when a request is sent to an endpoint that the API version of the config doesn't have.
+type UnsupportedEndpointError struct {
+	Version APIVersion
+	Path    string
+}
+
+func (r UnsupportedEndpointError) Error() string {
+	return fmt.Sprintf("BankID API version %s has no endpoint %s", r.Version, r.Path)
+}
+
+// UnsupportedFieldError is returned when a request contains a field that the API version of the config doesn't support.
+// The request is not sent to BankID.
+type UnsupportedFieldError struct {
+	Version APIVersion
+	Path    string
+	Field   string
+}
+
+func (r UnsupportedFieldError) Error() string {
+	return fmt.Sprintf("field %s of request %s is not supported by BankID API version %s", r.Field, r.Path, r.Version)
+}
+
+// UnsupportedAlgorithmError is returned when a signature uses an algorithm the SignatureVerifier doesn't support.
+type UnsupportedAlgorithmError struct {
+	Algorithm string
+}
+
+func (r UnsupportedAlgorithmError) Error() string {
+	return fmt.Sprintf("signature uses an unsupported algorithm: %s", r.Algorithm)
+}
+
+// MissingReferenceError is returned when a signature doesn't sign a part of the document exactly once, e.g. "#bidSignedData".
+type MissingReferenceError struct {
+	URI string
+}
+
+func (r MissingReferenceError) Error() string {
+	return fmt.Sprintf("signature has no single reference to %s", r.URI)
+}
+
+// DigestMismatchError is returned when a signed part of the document was changed after it was signed.
+type DigestMismatchError struct {
+	URI string
+}
+
+func (r DigestMismatchError) Error() string {
+	return fmt.Sprintf("digest of %s doesn't match the signed digest", r.URI)
+}
+
+// SignatureValueError is returned when the signature value wasn't created by the key of the user's certificate.
+type SignatureValueError struct {
+	Err error
+}
+
+func (r SignatureValueError) Error() string {
+	return fmt.Sprintf("invalid signature value: %v", r.Err)
+}
+
+func (r SignatureValueError) Unwrap() error {
+	return r.Err
+}
+
+// UntrustedCertificateError is returned when the user's certificate doesn't chain up to a trusted root.
+type UntrustedCertificateError struct {
+	Err error
+}
+
+func (r UntrustedCertificateError) Error() string {
+	return fmt.Sprintf("untrusted certificate: %v", r.Err)
+}
+
+func (r UntrustedCertificateError) Unwrap() error {
+	return r.Err
+}
+
+// OCSPResponderError is returned when the OCSP response isn't signed by the issuing CA of the user's certificate or a responder it certified.
+type OCSPResponderError struct {
+	Err error
+}
+
+func (r OCSPResponderError) Error() string {
+	return fmt.Sprintf("invalid ocsp responder: %v", r.Err)
+}
+
+func (r OCSPResponderError) Unwrap() error {
+	return r.Err
+}
+
+// OCSPStatusError is returned when the OCSP response doesn't report the user's certificate as good.
+type OCSPStatusError struct {
+	Status    string
+	RevokedAt time.Time
+}
+
+func (r OCSPStatusError) Error() string {
+	if r.Status == "revoked" {
+		return fmt.Sprintf("certificate was revoked at %s", r.RevokedAt.Format(time.RFC3339))
+	}
+
+	return fmt.Sprintf("certificate status is %s", r.Status)
+}
+
+// OCSPNonceError is returned when the nonce of the OCSP response isn't bound to the signature of the order.
+type OCSPNonceError struct{}
+
+func (r OCSPNonceError) Error() string {
+	return "ocsp nonce doesn't match the signature"
+}
+
+// CompletionMismatchError is returned by CheckCompletion when the signature of a completed order doesn't contain what the request
+// asked the user to sign. Field is the field that differs: "type", "userVisibleData", "userNonVisibleData" or "personalNumber".
+type CompletionMismatchError struct {
+	OrderRef string
+	Field    string
+}
+
+func (r CompletionMismatchError) Error() string {
+	return fmt.Sprintf("signature of order %s doesn't match the request: %s differs", r.OrderRef, r.Field)
+}
+
+// EvidenceIntegrityError is returned by VerifyBundle when a part of an evidence bundle doesn't match its manifest,
+// or doesn't match the collect response it was taken from.
+type EvidenceIntegrityError struct {
+	Part string
+}
+
+func (r EvidenceIntegrityError) Error() string {
+	return fmt.Sprintf("evidence bundle is damaged: %s doesn't match", r.Part)
+}
+
+// TransportError is returned when a request could not be sent to BankID or its response could not be read.
+type TransportError struct {
+	Path     string
+	OrderRef string
+	Err      error
+}
+
+func (r TransportError) Error() string {
+	return fmt.Sprintf("error request %s: %v", r.Path, r.Err)
+}
+
+func (r TransportError) Unwrap() error {
+	return r.Err
+}
+
+// The network failure is considered temporary unless the request was cancelled or its deadline exceeded,
+// or the TLS connection failed. TLS errors, e.g. an untrusted server certificate or a client certificate
+// that BankID rejects, fail again on every attempt.
+func (r TransportError) Temporary() bool {
+	if errors.Is(r.Err, context.Canceled) || errors.Is(r.Err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return !isTLSError(r.Err)
+}
+
+// The request may have reached BankID before it failed, so only requests that don't create an order can be sent again.
+func (r TransportError) Retryable() bool {
+	return r.Temporary() && !isOrderPath(r.Path)
 }
 
 // BankIDError is an error returned by BankID that should be communicated to the enduser, or handled by the RP.
+// Errors returned from requests keep the details and status code sent by BankID and match the ErrXxx values with errors.Is by their ErrorCode.
 type BankIDError struct {
 	StatusCode int       `json:"statusCode,omitempty"`
 	Details    string    `json:"details,omitempty"`
 	ErrorCode  ErrorCode `json:"errorCode,omitempty"`
+
+	// The path of the endpoint that returned the error, e.g. "/collect".
+	Path string `json:"path,omitempty"`
+
+	// The orderRef the request referred to, only set for requests that take an orderRef.
+	OrderRef string `json:"orderRef,omitempty"`
 }
 
 func (r BankIDError) Error() string {
-	return fmt.Sprintf("\n\nBankID \n- StatusCode: \t%d  \n- ErrorCode: \t%s \n- Details: \t%s\n\n", r.StatusCode, r.ErrorCode, r.Details)
+	var request string
+	if r.Path != "" {
+		request += fmt.Sprintf("- Path: \t%s \n", r.Path)
+	}
+	if r.OrderRef != "" {
+		request += fmt.Sprintf("- OrderRef: \t%s \n", r.OrderRef)
+	}
+
+	return fmt.Sprintf("\n\nBankID \n%s- StatusCode: \t%d  \n- ErrorCode: \t%s \n- Details: \t%s\n\n", request, r.StatusCode, r.ErrorCode, redactText(r.Details))
 }
 
+// Is reports whether the target is a BankIDError with the same ErrorCode, error codes unknown to this library match ErrUnknownErrorCode.
+func (r BankIDError) Is(target error) bool {
+	t, ok := target.(BankIDError)
+	if !ok {
+		return false
+	}
+
+	if t.ErrorCode == UnknownErrorCode {
+		return !r.ErrorCode.known()
+	}
+
+	return r.ErrorCode == t.ErrorCode
+}
+
+// Temporary reports whether the error is caused by a transient condition at BankID.
+func (r BankIDError) Temporary() bool {
+	switch r.ErrorCode {
+	case Maintenance, InternalError, RequestTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retryable reports whether the request that caused the error can be sent again automatically.
+// Only maintenance is retried, BankID guarantees that the request had no effect. After an internalError or
+// requestTimeout the state of the order is unknown, the RP decides whether to send the request again.
+func (r BankIDError) Retryable() bool {
+	return r.ErrorCode == Maintenance
+}
+
+// English texts of a few recommended user messages.
+//
+// Deprecated: use UserMessages, NewUserMessage or UserMessageForError which cover every message in Swedish and English.
 const (
 	RAF1  = "The user cancelled."
 	RFA4  = "An identification or signing for this personal number is already started. Please try again."
@@ -42,6 +381,16 @@ const (
 
 type ErrorCode string
 
+func (e ErrorCode) known() bool {
+	switch e {
+	case AlreadyInProgress, RequestTimeout, InternalError, Maintenance, InvalidParameters,
+		Unauthorized, NotFound, MethodNotAllowed, UnsupportedMediaType:
+		return true
+	default:
+		return false
+	}
+}
+
 const (
 	AlreadyInProgress    ErrorCode = "alreadyInProgress"
 	UnknownErrorCode     ErrorCode = "unknownErrorCode"
@@ -132,7 +481,30 @@ var (
 	}
 )
 
-func assignError(errorCode ErrorCode) BankIDError {
+// assignError combines the error response from BankID with the matching ErrXxx value.
+// The details and status code sent by BankID take precedence, the ErrXxx value fills in what's missing.
+func assignError(e ErrorResponseBody, statusCode int, path string, orderRef string) BankIDError {
+	err := knownError(e.ErrorCode)
+
+	if e.ErrorCode != "" {
+		err.ErrorCode = e.ErrorCode
+	}
+
+	if e.Details != "" {
+		err.Details = e.Details
+	}
+
+	if statusCode != 0 {
+		err.StatusCode = statusCode
+	}
+
+	err.Path = path
+	err.OrderRef = orderRef
+
+	return err
+}
+
+func knownError(errorCode ErrorCode) BankIDError {
 	switch errorCode {
 	case AlreadyInProgress:
 		return ErrAlreadyInProgress
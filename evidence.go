@@ -0,0 +1,270 @@
+package bankid
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The version of the evidence bundle format, increased when the format changes.
+const EvidenceBundleVersion = 1
+
+// EvidenceBundle is the self-contained record of a completed order, everything needed to verify the signature offline years later.
+// It's exported and read as JSON. Binary parts are base64 encoded by encoding/json.
+//
+// The manifest holds the SHA-256 hash of every part. The hashes detect damage to the archived bundle.
+// The signature of the user and the OCSP response prove what was signed, when, and by whom.
+type EvidenceBundle struct {
+	// The version of the bundle format, see EvidenceBundleVersion.
+	Version int `json:"version"`
+
+	// The time the bundle was exported.
+	CapturedAt time.Time `json:"capturedAt"`
+
+	// The path of the endpoint the request was sent to, e.g. "/sign".
+	RequestPath string `json:"requestPath"`
+
+	// The request that started the order, as passed to Auth, Sign, Phone or Payment.
+	Request json.RawMessage `json:"request"`
+
+	// The response of the collect request that completed the order.
+	CollectResponse json.RawMessage `json:"collectResponse"`
+
+	// The XML signature, decoded from CompletionData.Signature.
+	SignatureXML []byte `json:"signatureXml"`
+
+	// The DER encoded OCSP response, decoded from CompletionData.OcspResponse.
+	OCSPResponse []byte `json:"ocspResponse"`
+
+	// The DER encoded certificates of the signature, starting with the user's certificate followed by the CAs that issued it.
+	Certificates [][]byte `json:"certificates"`
+
+	// The SHA-256 hashes of the parts of the bundle.
+	Manifest []EvidenceFile `json:"manifest"`
+}
+
+// EvidenceFile is an entry of the manifest of an EvidenceBundle.
+type EvidenceFile struct {
+	// The name of the part, e.g. "signature.xml" or "certificates/0.der".
+	Name string `json:"name"`
+
+	// The hex encoded SHA-256 hash of the part.
+	SHA256 string `json:"sha256"`
+}
+
+// Export creates the evidence bundle of a completed order from the request that started it and the final collect response.
+// The bundle is returned as JSON, ready to be archived with the signed document.
+//
+// Example:
+//
+//	bundle, err := bankid.Export(signRequest, *collectResponse)
+//	...
+//	_, err = bankid.VerifyBundle(bundle, bankid.SignatureVerifier{Roots: pool})
+func Export[T RequestBody](request T, response CollectResponse) ([]byte, error) {
+	if response.Status != Complete {
+		return nil, InputInvalidError{Message: fmt.Sprintf("order %s is not complete, status: %s", response.OrderRef, response.Status)}
+	}
+
+	path, err := requestPath(request)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := ParseSignature(response.CompletionData.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	ocspResponse, err := decodeBase64(response.CompletionData.OcspResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding ocsp response: %w", err)
+	}
+
+	req, err := request.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request: %w", err)
+	}
+
+	collect, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling collect response: %w", err)
+	}
+
+	b := EvidenceBundle{
+		Version:         EvidenceBundleVersion,
+		CapturedAt:      time.Now().UTC(),
+		RequestPath:     path,
+		Request:         req,
+		CollectResponse: collect,
+		SignatureXML:    s.XML,
+		OCSPResponse:    ocspResponse,
+	}
+
+	for _, cert := range s.Certificates {
+		b.Certificates = append(b.Certificates, cert.Raw)
+	}
+
+	b.Manifest, err = b.manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// VerifyBundle reads an evidence bundle and verifies it offline:
+//   - The parts match the hashes of the manifest, and the signature, OCSP response and certificates are the ones of the collect response.
+//   - The XML signature and the OCSP response are verified with the verifier's VerifyCompletionData. The certificate chain
+//     is verified at the signing time of the verified OCSP response unless the verifier has a Time.
+//   - The signature contains what the request asked the user to sign, checked with CheckCompletion.
+//
+// A damaged or inconsistent bundle is returned as an EvidenceIntegrityError, other failures as the errors of the checks.
+func VerifyBundle(bundle []byte, verifier SignatureVerifier) (*EvidenceBundle, error) {
+	var b EvidenceBundle
+	err := json.Unmarshal(bundle, &b)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing evidence bundle: %w", err)
+	}
+
+	if b.Version != EvidenceBundleVersion {
+		return nil, InputInvalidError{Message: fmt.Sprintf("unsupported evidence bundle version: %d", b.Version)}
+	}
+
+	manifest, err := b.manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest) != len(b.Manifest) {
+		return nil, EvidenceIntegrityError{Part: "manifest"}
+	}
+	for i, f := range manifest {
+		if b.Manifest[i] != f {
+			return nil, EvidenceIntegrityError{Part: f.Name}
+		}
+	}
+
+	var response CollectResponse
+	err = json.Unmarshal(b.CollectResponse, &response)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing collect response: %w", err)
+	}
+
+	s, err := response.CompletionData.ParseSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	// the parts are copies of the collect response, kept so they can be read without decoding it
+	if !bytes.Equal(s.XML, b.SignatureXML) {
+		return nil, EvidenceIntegrityError{Part: "signature.xml"}
+	}
+
+	ocspResponse, err := decodeBase64(response.CompletionData.OcspResponse)
+	if err != nil || len(ocspResponse) == 0 || !bytes.Equal(ocspResponse, b.OCSPResponse) {
+		return nil, EvidenceIntegrityError{Part: "ocsp.der"}
+	}
+
+	if len(s.Certificates) != len(b.Certificates) {
+		return nil, EvidenceIntegrityError{Part: "certificates"}
+	}
+	for i, cert := range s.Certificates {
+		if !bytes.Equal(cert.Raw, b.Certificates[i]) {
+			return nil, EvidenceIntegrityError{Part: fmt.Sprintf("certificates/%d.der", i)}
+		}
+	}
+
+	_, err = verifier.VerifyCompletionData(response.CompletionData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.checkCompletion(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// checkCompletion decodes the request of the bundle by its path and checks it against the signature.
+func (b EvidenceBundle) checkCompletion(response CollectResponse) error {
+	switch b.RequestPath {
+	case "/auth":
+		return checkBundledRequest[AuthRequest](b.Request, response)
+	case "/sign":
+		return checkBundledRequest[SignRequest](b.Request, response)
+	case "/phone/auth":
+		return checkBundledRequest[PhoneAuthRequest](b.Request, response)
+	case "/phone/sign":
+		return checkBundledRequest[PhoneSignRequest](b.Request, response)
+	case "/payment":
+		return checkBundledRequest[PaymentRequest](b.Request, response)
+	}
+
+	return InputInvalidError{Message: fmt.Sprintf("unknown request path in evidence bundle: %s", b.RequestPath)}
+}
+
+func checkBundledRequest[T RequestBody](data []byte, response CollectResponse) error {
+	var request T
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		return fmt.Errorf("error parsing request: %w", err)
+	}
+
+	_, err = CheckCompletion(request, response)
+	return err
+}
+
+// manifest returns the hashes of the parts of the bundle. JSON parts are hashed in compact form, so indentation doesn't change them.
+func (b EvidenceBundle) manifest() ([]EvidenceFile, error) {
+	var files []EvidenceFile
+	add := func(name string, data []byte) {
+		sum := sha256.Sum256(data)
+		files = append(files, EvidenceFile{Name: name, SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	for _, part := range []struct {
+		name string
+		data json.RawMessage
+	}{
+		{"request.json", b.Request},
+		{"collect.json", b.CollectResponse},
+	} {
+		var compact bytes.Buffer
+		err := json.Compact(&compact, part.data)
+		if err != nil {
+			return nil, EvidenceIntegrityError{Part: part.name}
+		}
+		add(part.name, compact.Bytes())
+	}
+
+	add("signature.xml", b.SignatureXML)
+	add("ocsp.der", b.OCSPResponse)
+	for i, cert := range b.Certificates {
+		add(fmt.Sprintf("certificates/%d.der", i), cert)
+	}
+
+	return files, nil
+}
+
+// requestPath returns the path of the endpoint that starts the order of a request.
+func requestPath(rb RequestBody) (string, error) {
+	switch rb.(type) {
+	case AuthRequest:
+		return "/auth", nil
+	case SignRequest:
+		return "/sign", nil
+	case PhoneAuthRequest:
+		return "/phone/auth", nil
+	case PhoneSignRequest:
+		return "/phone/sign", nil
+	case PaymentRequest:
+		return "/payment", nil
+	}
+
+	return "", InputInvalidError{Message: fmt.Sprintf("request %T doesn't create an order with a signature", rb)}
+}
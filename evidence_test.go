@@ -0,0 +1,84 @@
+package bankid
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestEvidenceBundle(t *testing.T) {
+	pki := newTestPKI(t)
+	signature := base64.StdEncoding.EncodeToString([]byte(pki.signatureXML("Signing", "Jag godkänner avtalet", nil)))
+
+	hash := sha1.Sum([]byte(signature))
+	nonce := append(hash[:], make([]byte, 12)...)
+
+	request := SignRequest{
+		EndUserIP:       "192.168.1.1",
+		UserVisibleData: "Jag godkänner avtalet",
+		Requirement:     &Requirement{PersonalNumber: "199510221287"},
+	}
+
+	response := CollectResponse{
+		OrderRef: "131daac9-16c6-4618-beb0-365768f37288",
+		Status:   Complete,
+		CompletionData: CompletionData{
+			User:         User{PersonalNumber: "199510221287", Name: "Karl Karlsson"},
+			Signature:    signature,
+			OcspResponse: pki.ocspResponse(t, ocsp.Good, nonce, pki.issuer, pki.issuerKey),
+		},
+	}
+
+	bundle, err := Export(request, response)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.root)
+	verifier := SignatureVerifier{Roots: roots}
+
+	b, err := VerifyBundle(bundle, verifier)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if b.RequestPath != "/sign" || len(b.Certificates) != 3 || len(b.Manifest) != 7 || b.CapturedAt.IsZero() {
+		t.Errorf("unexpected bundle: %s %d certificates %d files %v", b.RequestPath, len(b.Certificates), len(b.Manifest), b.CapturedAt)
+	}
+
+	// the bundle changed after it was exported
+	var damaged EvidenceBundle
+	_ = json.Unmarshal(bundle, &damaged)
+	damaged.OCSPResponse[len(damaged.OCSPResponse)-1] ^= 0xff
+	data, _ := json.Marshal(damaged)
+
+	var integrity EvidenceIntegrityError
+	_, err = VerifyBundle(data, verifier)
+	if !errors.As(err, &integrity) || integrity.Part != "ocsp.der" {
+		t.Errorf("expected the damaged ocsp response, got %v", err)
+	}
+
+	// a consistent bundle of a request the user didn't sign
+	request.UserVisibleData = "Jag godkänner inte avtalet"
+	bundle, err = Export(request, response)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var mismatch CompletionMismatchError
+	_, err = VerifyBundle(bundle, verifier)
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected a mismatch of the request, got %v", err)
+	}
+
+	_, err = VerifyBundle(bundle, SignatureVerifier{Roots: x509.NewCertPool()})
+	if !errors.As(err, &UntrustedCertificateError{}) {
+		t.Errorf("expected an untrusted certificate, got %v", err)
+	}
+}
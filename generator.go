@@ -0,0 +1,171 @@
+package bankid
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// GeneratorOption configures a PersonnummerGenerator
+type GeneratorOption func(*PersonnummerGenerator)
+
+// WithSeed makes the generator deterministic, the same seed generates the same numbers.
+func WithSeed(seed uint64) GeneratorOption {
+	return func(g *PersonnummerGenerator) {
+		g.rand = rand.New(rand.NewPCG(seed, seed))
+	}
+}
+
+// Personnummer were introduced in 1947 for everyone registered in Sweden. No one born before 1830 was alive then,
+// so the numbers of earlier dates of birth have never been assigned to anyone.
+var (
+	firstGeneratedBirthDate = time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastGeneratedBirthDate  = time.Date(1829, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// WithBirthDates limits the dates of birth to the range from and to, both included. The range is kept within
+// 1800-01-01 to 1829-12-31, the numbers of later dates of birth may belong to real people.
+func WithBirthDates(from, to time.Time) GeneratorOption {
+	clamp := func(t time.Time) time.Time {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		if t.Before(firstGeneratedBirthDate) {
+			return firstGeneratedBirthDate
+		}
+		if t.After(lastGeneratedBirthDate) {
+			return lastGeneratedBirthDate
+		}
+		return t
+	}
+
+	return func(g *PersonnummerGenerator) {
+		g.from, g.to = clamp(from), clamp(to)
+	}
+}
+
+// WithGender generates numbers of one legal gender only.
+func WithGender(gender Gender) GeneratorOption {
+	return func(g *PersonnummerGenerator) {
+		g.gender = gender
+	}
+}
+
+// WithSamordningsnummer generates samordningsnummer, the day of birth plus 60, instead of personnummer.
+func WithSamordningsnummer() GeneratorOption {
+	return func(g *PersonnummerGenerator) {
+		g.samordningsnummer = true
+	}
+}
+
+// PersonnummerGenerator generates valid personnummer and samordningsnummer for tests that can never belong to real people.
+// The dates of birth are before 1830, so no one had a personnummer when they were introduced in 1947.
+// The birth numbers are not restricted, no range of birth numbers is reserved for tests.
+//
+// Example:
+//
+//	g := bankid.NewPersonnummerGenerator(bankid.WithSeed(42), bankid.WithGender(bankid.GenderFemale))
+//
+//	for _, p := range g.Generate(1000) {
+//		fmt.Println(p) // e.g. 181705149829
+//	}
+type PersonnummerGenerator struct {
+	rand              *rand.Rand
+	from              time.Time
+	to                time.Time
+	gender            Gender
+	samordningsnummer bool
+}
+
+// NewPersonnummerGenerator returns a generator of random numbers of people born from 1800-01-01 to 1829-12-31.
+func NewPersonnummerGenerator(opts ...GeneratorOption) *PersonnummerGenerator {
+	g := &PersonnummerGenerator{
+		rand: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		from: firstGeneratedBirthDate,
+		to:   lastGeneratedBirthDate,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Next returns the next number of the generator.
+func (g *PersonnummerGenerator) Next() Personnummer {
+	days := int(g.to.Sub(g.from).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+	birthDate := g.from.AddDate(0, 0, g.rand.IntN(days+1))
+
+	// the last digit of the birth number is odd for men and even for women
+	var parity int
+	switch g.gender {
+	case GenderMale:
+		parity = 1
+	case GenderFemale:
+		parity = 0
+	default:
+		parity = g.rand.IntN(2)
+	}
+
+	// 000 isn't a birth number
+	birthNumber := 2*g.rand.IntN(500) + parity
+	if birthNumber == 0 {
+		birthNumber = 2 * (g.rand.IntN(499) + 1)
+	}
+
+	day := birthDate.Day()
+	if g.samordningsnummer {
+		day += 60
+	}
+
+	partial := fmt.Sprintf("%s%02d%03d", birthDate.Format("0601"), day, birthNumber)
+	checkDigit, _ := CheckDigit(partial)
+
+	return Personnummer{
+		birthDate:         birthDate,
+		serial:            fmt.Sprintf("%03d%d", birthNumber, checkDigit),
+		samordningsnummer: g.samordningsnummer,
+	}
+}
+
+// Generate returns the next n numbers of the generator.
+func (g *PersonnummerGenerator) Generate(n int) []Personnummer {
+	numbers := make([]Personnummer, n)
+	for i := range numbers {
+		numbers[i] = g.Next()
+	}
+
+	return numbers
+}
+
+// CheckDigit calculates the check digit of a personnummer, samordningsnummer or organisationsnummer without its last digit:
+// YYMMDDNNN, YYMMDD-NNN, YYYYMMDDNNN or YYYYMMDD-NNN. The check digit is calculated over the 10 digit format.
+func CheckDigit(partial string) (int, error) {
+	s, _ := stripIdentityNumber(partial + "0")
+	s = s[:len(s)-1]
+
+	if !isDigits(s) || (len(s) != 9 && len(s) != 11) {
+		return 0, InputInvalidError{Message: "a check digit is calculated for 9 or 11 digits"}
+	}
+
+	if len(s) == 11 {
+		s = s[2:]
+	}
+
+	// the luhn algorithm doubles every second digit starting with the first
+	sum := 0
+	for i, ch := range s {
+		num := int(ch - '0')
+		if i%2 == 0 {
+			num *= 2
+			if num > 9 {
+				num -= 9
+			}
+		}
+		sum += num
+	}
+
+	return (10 - sum%10) % 10, nil
+}
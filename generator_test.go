@@ -0,0 +1,78 @@
+package bankid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPersonnummerGenerator(t *testing.T) {
+	from := time.Date(1820, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(1824, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	g := NewPersonnummerGenerator(WithSeed(42), WithBirthDates(from, to), WithGender(GenderFemale))
+	numbers := g.Generate(1000)
+
+	for _, p := range numbers {
+		parsed, err := ParsePersonnummer(p.String())
+		if err != nil {
+			t.Fatalf("generated an invalid personnummer %s: %v", p, err)
+		}
+
+		if parsed.Gender() != GenderFemale || parsed.BirthDate().Before(from) || parsed.BirthDate().After(to) {
+			t.Errorf("unexpected personnummer %s", p)
+		}
+
+		if birthNumber := p.String()[8:11]; birthNumber == "000" {
+			t.Errorf("expected a birth number from 001, got %s", p)
+		}
+
+		if err := validateChecksum(p.String()[2:]); err != nil {
+			t.Errorf("unexpected checksum error for %s: %v", p, err)
+		}
+	}
+
+	again := NewPersonnummerGenerator(WithSeed(42), WithBirthDates(from, to), WithGender(GenderFemale)).Generate(1000)
+	for i := range numbers {
+		if numbers[i] != again[i] {
+			t.Fatalf("expected the same numbers for the same seed, got %s and %s", numbers[i], again[i])
+		}
+	}
+
+	for _, p := range NewPersonnummerGenerator(WithSamordningsnummer(), WithGender(GenderMale)).Generate(1000) {
+		parsed, err := ParsePersonnummer(p.String())
+		if err != nil || parsed.Kind() != KindSamordningsnummer || parsed.Gender() != GenderMale || p.String()[8:11] == "000" {
+			t.Errorf("unexpected samordningsnummer %s: %v", p, err)
+		}
+	}
+
+	// no number may belong to a real person, born after 1829
+	recent := NewPersonnummerGenerator(WithBirthDates(time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)))
+	for _, p := range append(recent.Generate(100), NewPersonnummerGenerator().Generate(1000)...) {
+		if p.BirthDate().After(lastGeneratedBirthDate) {
+			t.Errorf("expected a date of birth before 1830, got %s", p)
+		}
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	digits := map[string]int{
+		"951022128":    7,
+		"951022-128":   7,
+		"19951022128":  7,
+		"19121212-121": 2,
+		"556016068":    0,
+	}
+
+	for partial, expected := range digits {
+		digit, err := CheckDigit(partial)
+		if err != nil || digit != expected {
+			t.Errorf("expected %d for %q, got %d %v", expected, partial, digit, err)
+		}
+	}
+
+	for _, partial := range []string{"", "95102212", "95102212a"} {
+		if _, err := CheckDigit(partial); err == nil {
+			t.Errorf("expected an error for %q", partial)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package bankid
+
+import "errors"
+
+// Language of a user message, as a BCP 47 language tag.
+type Language string
+
+const (
+	Swedish Language = "sv"
+	English Language = "en"
+)
+
+// Flow describes how the user was asked to start the BankID app, the recommended user messages differ between flows.
+type Flow string
+
+const (
+	// The user scans an animated QR code with the BankID app on another device.
+	FlowQRCode Flow = "qrCode"
+
+	// The BankID app is started with the autoStartToken on the same device as the RP's service.
+	FlowSameDevice Flow = "sameDevice"
+
+	// The order was started with PhoneAuth or PhoneSign while the user talks to the RP over the phone.
+	FlowPhone Flow = "phone"
+)
+
+// UserMessageCode identifies a message that BankID recommends RPs to show to the user.
+// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/anvandarmeddelanden
+type UserMessageCode string
+
+const (
+	UserMessageRFA1   UserMessageCode = "RFA1"
+	UserMessageRFA2   UserMessageCode = "RFA2"
+	UserMessageRFA3   UserMessageCode = "RFA3"
+	UserMessageRFA4   UserMessageCode = "RFA4"
+	UserMessageRFA5   UserMessageCode = "RFA5"
+	UserMessageRFA6   UserMessageCode = "RFA6"
+	UserMessageRFA8   UserMessageCode = "RFA8"
+	UserMessageRFA9   UserMessageCode = "RFA9"
+	UserMessageRFA13  UserMessageCode = "RFA13"
+	UserMessageRFA14A UserMessageCode = "RFA14A"
+	UserMessageRFA14B UserMessageCode = "RFA14B"
+	UserMessageRFA15A UserMessageCode = "RFA15A"
+	UserMessageRFA15B UserMessageCode = "RFA15B"
+	UserMessageRFA16  UserMessageCode = "RFA16"
+	UserMessageRFA17A UserMessageCode = "RFA17A"
+	UserMessageRFA17B UserMessageCode = "RFA17B"
+	UserMessageRFA18  UserMessageCode = "RFA18"
+	UserMessageRFA19  UserMessageCode = "RFA19"
+	UserMessageRFA20  UserMessageCode = "RFA20"
+	UserMessageRFA21  UserMessageCode = "RFA21"
+	UserMessageRFA22  UserMessageCode = "RFA22"
+	UserMessageRFA23  UserMessageCode = "RFA23"
+
+	// The message of this library for userCallConfirm, the user confirms in the app that they are in a call with the RP.
+	UserMessageCallConfirm UserMessageCode = "callConfirm"
+)
+
+// UserMessages holds the recommended user messages per language.
+// Other languages can be supported by adding their texts before the messages are used, e.g.
+//
+//	bankid.UserMessages["de"] = map[bankid.UserMessageCode]string{bankid.UserMessageRFA1: "Starten Sie die BankID-App."}
+//
+// Codes that are missing in a language fall back to English.
+var UserMessages = map[Language]map[UserMessageCode]string{
+	Swedish: {
+		UserMessageRFA1:   "Starta BankID-appen.",
+		UserMessageRFA2:   "Du har inte BankID-appen installerad. Kontakta din bank.",
+		UserMessageRFA3:   "Åtgärden avbruten. Försök igen.",
+		UserMessageRFA4:   "En identifiering eller underskrift för det här personnumret är redan påbörjad. Försök igen.",
+		UserMessageRFA5:   "Internt tekniskt fel. Försök igen.",
+		UserMessageRFA6:   "Åtgärden avbruten.",
+		UserMessageRFA8:   "BankID-appen svarar inte. Kontrollera att den är startad och att du har internetanslutning. Om du inte har något giltigt BankID kan du skaffa ett hos din bank. Försök sedan igen.",
+		UserMessageRFA9:   "Skriv in din säkerhetskod i BankID-appen och välj Identifiera eller Skriv under.",
+		UserMessageRFA13:  "Försöker starta BankID-appen.",
+		UserMessageRFA14A: "Söker efter BankID, det kan ta en liten stund… Om det har gått några sekunder och inget BankID har hittats har du sannolikt inget BankID som går att använda för den aktuella identifieringen/underskriften i den här datorn. Om du har ett BankID-kort, sätt in det i kortläsaren. Om du inte har något BankID kan du skaffa ett hos din bank. Om du har ett BankID på en annan enhet kan du starta din BankID-app där.",
+		UserMessageRFA14B: "Söker efter BankID, det kan ta en liten stund… Om det har gått några sekunder och inget BankID har hittats har du sannolikt inget BankID som går att använda för den aktuella identifieringen/underskriften i den här enheten. Om du inte har något BankID kan du skaffa ett hos din bank. Om du har ett BankID på en annan enhet kan du starta din BankID-app där.",
+		UserMessageRFA15A: "Söker efter BankID, det kan ta en liten stund… Om det har gått några sekunder och inget BankID har hittats har du sannolikt inget BankID som går att använda för den aktuella identifieringen/underskriften i den här datorn. Om du har ett BankID-kort, sätt in det i kortläsaren. Om du inte har något BankID kan du skaffa ett hos din bank.",
+		UserMessageRFA15B: "Söker efter BankID, det kan ta en liten stund… Om det har gått några sekunder och inget BankID har hittats har du sannolikt inget BankID som går att använda för den aktuella identifieringen/underskriften i den här enheten. Om du inte har något BankID kan du skaffa ett hos din bank.",
+		UserMessageRFA16:  "Det BankID du försöker använda är för gammalt eller spärrat. Använd ett annat BankID eller skaffa ett nytt hos din bank.",
+		UserMessageRFA17A: "BankID-appen verkar inte finnas i din dator eller mobil. Installera den och skaffa ett BankID hos din bank. Installera appen från din appbutik eller https://install.bankid.com.",
+		UserMessageRFA17B: "Misslyckades att läsa av QR-koden. Starta BankID-appen och läs av QR-koden. Kontrollera att BankID-appen är uppdaterad. Om du inte har BankID-appen måste du installera den och skaffa ett BankID hos din bank. Installera appen från din appbutik eller https://install.bankid.com.",
+		UserMessageRFA18:  "Starta BankID-appen",
+		UserMessageRFA19:  "Vill du identifiera dig eller skriva under med BankID på den här datorn eller med ett BankID på en annan enhet?",
+		UserMessageRFA20:  "Vill du identifiera dig eller skriva under med ett BankID på den här enheten eller med ett BankID på en annan enhet?",
+		UserMessageRFA21:  "Identifiering eller underskrift pågår.",
+		UserMessageRFA22:  "Okänt fel. Försök igen.",
+		UserMessageRFA23:  "Fotografera och läs av din ID-handling med BankID-appen.",
+
+		UserMessageCallConfirm: "Bekräfta i BankID-appen att du pratar med oss i telefon.",
+	},
+	English: {
+		UserMessageRFA1:   "Start your BankID app.",
+		UserMessageRFA2:   "The BankID app is not installed. Please contact your bank.",
+		UserMessageRFA3:   "Action cancelled. Please try again.",
+		UserMessageRFA4:   RFA4,
+		UserMessageRFA5:   RFA5,
+		UserMessageRFA6:   "Action cancelled.",
+		UserMessageRFA8:   "The BankID app is not responding. Please check that it's started and that you have internet access. If you don't have a valid BankID you can get one from your bank. Try again.",
+		UserMessageRFA9:   "Enter your security code in the BankID app and select Identify or Sign.",
+		UserMessageRFA13:  "Trying to start your BankID app.",
+		UserMessageRFA14A: "Searching for BankID, it may take a little while … If a few seconds have passed and still no BankID has been found, you probably don't have a BankID which can be used for this identification/signing on this computer. If you have a BankID card, please insert it into your card reader. If you don't have a BankID you can get one from your bank. If you have a BankID on another device you can start the BankID app on that device.",
+		UserMessageRFA14B: "Searching for BankID, it may take a little while … If a few seconds have passed and still no BankID has been found, you probably don't have a BankID which can be used for this identification/signing on this device. If you don't have a BankID you can get one from your bank. If you have a BankID on another device you can start the BankID app on that device.",
+		UserMessageRFA15A: "Searching for BankID:s, it may take a little while … If a few seconds have passed and still no BankID has been found, you probably don't have a BankID which can be used for this identification/signing on this computer. If you have a BankID card, please insert it into your card reader. If you don't have a BankID you can get one from your bank.",
+		UserMessageRFA15B: "Searching for BankID:s, it may take a little while … If a few seconds have passed and still no BankID has been found, you probably don't have a BankID which can be used for this identification/signing on this device. If you don't have a BankID you can get one from your bank.",
+		UserMessageRFA16:  "The BankID you are trying to use is blocked or too old. Please use another BankID or get a new one from your bank.",
+		UserMessageRFA17A: "The BankID app couldn't be found on your computer or mobile device. Please install it and get a BankID from your bank. Install the app from your app store or https://install.bankid.com.",
+		UserMessageRFA17B: "Failed to scan the QR code. Start the BankID app and scan the QR code. Check that the BankID app is up to date. If you don't have the BankID app, you need to install it and get a BankID from your bank. Install the app from your app store or https://install.bankid.com.",
+		UserMessageRFA18:  "Start the BankID app",
+		UserMessageRFA19:  "Would you like to identify yourself or sign with a BankID on this computer, or with a BankID on another device?",
+		UserMessageRFA20:  "Would you like to identify yourself or sign with a BankID on this device or with a BankID on another device?",
+		UserMessageRFA21:  "Identification or signing in progress.",
+		UserMessageRFA22:  RFA22,
+		UserMessageRFA23:  "Process your machine-readable travel document using the BankID app.",
+
+		UserMessageCallConfirm: "Confirm in the BankID app that you are on the phone with us.",
+	},
+}
+
+// UserMessage is a recommended message to show to the user, in the requested language.
+type UserMessage struct {
+	Code     UserMessageCode `json:"code"`
+	Language Language        `json:"language"`
+	Text     string          `json:"text"`
+}
+
+// MessageOptions describe the context in which a user message is shown.
+type MessageOptions struct {
+	// Optional: How the user was asked to start the BankID app.
+	// Default: FlowQRCode
+	Flow Flow
+
+	// Optional: The language of the message, codes that aren't translated fall back to English.
+	// Default: Swedish
+	Language Language
+
+	// Optional: Set when the user is on a mobile device, selects the variant of RFA14 and RFA15 that doesn't mention card readers.
+	Mobile bool
+}
+
+func (o MessageOptions) withDefaults() MessageOptions {
+	if o.Flow == "" {
+		o.Flow = FlowQRCode
+	}
+
+	if o.Language == "" {
+		o.Language = Swedish
+	}
+
+	return o
+}
+
+// NewUserMessage returns the text of the user message in the language, falling back to English.
+func NewUserMessage(code UserMessageCode, language Language) UserMessage {
+	if text, ok := UserMessages[language][code]; ok {
+		return UserMessage{Code: code, Language: language, Text: text}
+	}
+
+	return UserMessage{Code: code, Language: English, Text: UserMessages[English][code]}
+}
+
+// UserMessageForCollect returns the message BankID recommends to show for the status and hint code of a collect response.
+// A completed order has no message, the zero UserMessage is returned and the RP shows the result of the order instead.
+// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
+func UserMessageForCollect(r *CollectResponse, opts MessageOptions) UserMessage {
+	opts = opts.withDefaults()
+
+	var code UserMessageCode
+	switch r.Status {
+	case Pending:
+		code = pendingMessage(r.HintCode, opts)
+	case Failed:
+		code = failedMessage(r.HintCode, opts)
+	case Complete:
+		return UserMessage{}
+	default:
+		code = UserMessageRFA22
+	}
+
+	return NewUserMessage(code, opts.Language)
+}
+
+// UserMessageForError returns the message BankID recommends to show for an error returned by this library.
+// Errors that are internal to the RP, e.g. invalidParameters, must not be shown as a BankID error so RFA22 is returned.
+func UserMessageForError(err error, opts MessageOptions) UserMessage {
+	opts = opts.withDefaults()
+
+	var failed OrderFailedError
+	if errors.As(err, &failed) {
+		return NewUserMessage(failedMessage(failed.HintCode, opts), opts.Language)
+	}
+
+	var bankIDErr BankIDError
+	if errors.As(err, &bankIDErr) {
+		switch bankIDErr.ErrorCode {
+		case AlreadyInProgress:
+			return NewUserMessage(UserMessageRFA4, opts.Language)
+		case InternalError, Maintenance, RequestTimeout:
+			return NewUserMessage(UserMessageRFA5, opts.Language)
+		}
+	}
+
+	var transportErr TransportError
+	if errors.As(err, &transportErr) {
+		return NewUserMessage(UserMessageRFA5, opts.Language)
+	}
+
+	return NewUserMessage(UserMessageRFA22, opts.Language)
+}
+
+func pendingMessage(hintCode HintCode, opts MessageOptions) UserMessageCode {
+	switch hintCode {
+	case OutstandingTransaction:
+		if opts.Flow == FlowSameDevice {
+			return UserMessageRFA13
+		}
+		return UserMessageRFA1
+	case NoClient:
+		return UserMessageRFA1
+	case Started:
+		switch {
+		// the user started the app on their own device, the messages about this computer or device don't apply
+		case opts.Flow == FlowPhone:
+			return UserMessageRFA21
+		case opts.Flow == FlowSameDevice && opts.Mobile:
+			return UserMessageRFA15B
+		case opts.Flow == FlowSameDevice:
+			return UserMessageRFA15A
+		case opts.Mobile:
+			return UserMessageRFA14B
+		default:
+			return UserMessageRFA14A
+		}
+	case UserMrtd:
+		return UserMessageRFA23
+	case UserCallConfirm:
+		return UserMessageCallConfirm
+	case UserSign:
+		return UserMessageRFA9
+	default:
+		return UserMessageRFA21
+	}
+}
+
+func failedMessage(hintCode HintCode, opts MessageOptions) UserMessageCode {
+	switch hintCode {
+	case ExpiredTransaction:
+		return UserMessageRFA8
+	case CertificateErr:
+		return UserMessageRFA16
+	case UserCancel, UserDeclinedCall:
+		return UserMessageRFA6
+	case Cancelled:
+		return UserMessageRFA3
+	case StartFailed:
+		// only a QR code can fail to be scanned, the app wasn't found in the other flows
+		if opts.Flow == FlowQRCode {
+			return UserMessageRFA17B
+		}
+		return UserMessageRFA17A
+	default:
+		return UserMessageRFA22
+	}
+}
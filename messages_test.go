@@ -0,0 +1,103 @@
+package bankid
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUserMessageForCollect(t *testing.T) {
+	for _, tt := range []struct {
+		status   Status
+		hintCode HintCode
+		opts     MessageOptions
+		expected UserMessageCode
+	}{
+		{Pending, OutstandingTransaction, MessageOptions{Flow: FlowQRCode}, UserMessageRFA1},
+		{Pending, OutstandingTransaction, MessageOptions{Flow: FlowSameDevice}, UserMessageRFA13},
+		{Pending, Started, MessageOptions{Flow: FlowQRCode}, UserMessageRFA14A},
+		{Pending, Started, MessageOptions{Flow: FlowSameDevice, Mobile: true}, UserMessageRFA15B},
+		{Pending, UserSign, MessageOptions{Flow: FlowPhone}, UserMessageRFA9},
+		{Pending, UserMrtd, MessageOptions{}, UserMessageRFA23},
+		{Pending, "someNewHintCode", MessageOptions{}, UserMessageRFA21},
+		{Failed, StartFailed, MessageOptions{Flow: FlowQRCode}, UserMessageRFA17B},
+		{Failed, StartFailed, MessageOptions{Flow: FlowSameDevice}, UserMessageRFA17A},
+		{Failed, UserCancel, MessageOptions{}, UserMessageRFA6},
+		{Failed, ExpiredTransaction, MessageOptions{}, UserMessageRFA8},
+		{Failed, "someNewHintCode", MessageOptions{}, UserMessageRFA22},
+	} {
+		t.Run(fmt.Sprintf("%s %s %s", tt.status, tt.hintCode, tt.opts.Flow), func(t *testing.T) {
+			m := UserMessageForCollect(&CollectResponse{Status: tt.status, HintCode: tt.hintCode}, tt.opts)
+			if m.Code != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, m.Code)
+			}
+
+			if m.Language != Swedish || m.Text == "" {
+				t.Errorf("expected a Swedish text by default, got %+v", m)
+			}
+		})
+	}
+}
+
+func TestUserMessageForCollectFlows(t *testing.T) {
+	flows := []Flow{FlowQRCode, FlowSameDevice, FlowPhone}
+
+	for _, tt := range []struct {
+		status   Status
+		hintCode HintCode
+		expected [3]UserMessageCode // per flow: QR code, same device, phone
+	}{
+		{Pending, OutstandingTransaction, [3]UserMessageCode{UserMessageRFA1, UserMessageRFA13, UserMessageRFA1}},
+		{Pending, NoClient, [3]UserMessageCode{UserMessageRFA1, UserMessageRFA1, UserMessageRFA1}},
+		{Pending, Started, [3]UserMessageCode{UserMessageRFA14A, UserMessageRFA15A, UserMessageRFA21}},
+		{Pending, UserMrtd, [3]UserMessageCode{UserMessageRFA23, UserMessageRFA23, UserMessageRFA23}},
+		{Pending, UserCallConfirm, [3]UserMessageCode{UserMessageCallConfirm, UserMessageCallConfirm, UserMessageCallConfirm}},
+		{Pending, UserSign, [3]UserMessageCode{UserMessageRFA9, UserMessageRFA9, UserMessageRFA9}},
+		{Failed, ExpiredTransaction, [3]UserMessageCode{UserMessageRFA8, UserMessageRFA8, UserMessageRFA8}},
+		{Failed, CertificateErr, [3]UserMessageCode{UserMessageRFA16, UserMessageRFA16, UserMessageRFA16}},
+		{Failed, UserCancel, [3]UserMessageCode{UserMessageRFA6, UserMessageRFA6, UserMessageRFA6}},
+		{Failed, UserDeclinedCall, [3]UserMessageCode{UserMessageRFA6, UserMessageRFA6, UserMessageRFA6}},
+		{Failed, Cancelled, [3]UserMessageCode{UserMessageRFA3, UserMessageRFA3, UserMessageRFA3}},
+		{Failed, StartFailed, [3]UserMessageCode{UserMessageRFA17B, UserMessageRFA17A, UserMessageRFA17A}},
+		{Complete, "", [3]UserMessageCode{"", "", ""}},
+	} {
+		for i, flow := range flows {
+			m := UserMessageForCollect(&CollectResponse{Status: tt.status, HintCode: tt.hintCode}, MessageOptions{Flow: flow, Language: English})
+			if m.Code != tt.expected[i] {
+				t.Errorf("%s %s %s: expected %s, got %s", tt.status, tt.hintCode, flow, tt.expected[i], m.Code)
+			}
+
+			if (m.Text == "") != (tt.expected[i] == "") {
+				t.Errorf("%s %s %s: unexpected text %q", tt.status, tt.hintCode, flow, m.Text)
+			}
+		}
+	}
+}
+
+func TestUserMessageForError(t *testing.T) {
+	m := UserMessageForError(BankIDError{ErrorCode: Maintenance}, MessageOptions{Language: English})
+	if m.Code != UserMessageRFA5 || m.Text != "Internal error. Please try again." {
+		t.Errorf("unexpected message for maintenance: %+v", m)
+	}
+
+	m = UserMessageForError(ErrInvalidParameters, MessageOptions{})
+	if m.Code != UserMessageRFA22 {
+		t.Errorf("expected RP internal errors to use RFA22, got %s", m.Code)
+	}
+
+	m = UserMessageForError(OrderFailedError{HintCode: CertificateErr}, MessageOptions{Language: "de"})
+	if m.Code != UserMessageRFA16 || m.Language != English {
+		t.Errorf("expected untranslated languages to fall back to English, got %+v", m)
+	}
+}
+
+func TestUserMessagesComplete(t *testing.T) {
+	for code := range UserMessages[English] {
+		if UserMessages[Swedish][code] == "" {
+			t.Errorf("missing Swedish text for %s", code)
+		}
+	}
+
+	if len(UserMessages[English]) != len(UserMessages[Swedish]) {
+		t.Errorf("expected the same messages in Swedish and English")
+	}
+}
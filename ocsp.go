@@ -0,0 +1,230 @@
+package bankid
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// The OID of the OCSP nonce extension, RFC 8954.
+var oidOCSPNonce = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+// The structure of an OCSP response up to its response extensions, golang.org/x/crypto/ocsp only exposes the extensions of the single response.
+// Specification: https://www.rfc-editor.org/rfc/rfc6960#section-4.2.1
+type ocspResponseASN1 struct {
+	Status   asn1.Enumerated
+	Response struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	} `asn1:"explicit,tag:0,optional"`
+}
+
+type basicOCSPResponseASN1 struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseDataASN1 struct {
+	Version     int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   asn1.RawValue
+	Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// OCSPResponse is the OCSP response returned in CompletionData.OcspResponse.
+// It proves the user's certificate was valid when the user signed.
+type OCSPResponse struct {
+	// The DER encoded response, decoded from base64.
+	Raw []byte
+
+	// The status of the user's certificate: "good", "revoked" or "unknown".
+	Status string
+
+	// The serial number of the user's certificate.
+	SerialNumber *big.Int
+
+	// The time the responder signed the response, the time of the signature.
+	ProducedAt time.Time
+
+	// The time the status was known to be correct.
+	ThisUpdate time.Time
+
+	// The distinguished name of the responder, empty when a parsed response identifies the responder by its key.
+	ResponderName pkix.Name
+
+	// The certificate of the responder, nil when the issuing CA signed the response itself.
+	Responder *x509.Certificate
+
+	// The nonce of the response: the SHA-1 hash of the signature followed by 12 random bytes.
+	Nonce []byte
+}
+
+// ParseOCSPResponse decodes the base64 encoded OCSP response of CompletionData.OcspResponse without verifying it.
+func ParseOCSPResponse(ocspResponse string) (*OCSPResponse, error) {
+	der, err := decodeBase64(ocspResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding ocsp response: %w", err)
+	}
+
+	r, err := ocsp.ParseResponse(der, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ocsp response: %w", err)
+	}
+
+	return newOCSPResponse(der, r)
+}
+
+// VerifyOCSPResponse verifies the OCSP response of a completed order against the user's certificate in the signature.
+//   - The response is signed by the issuing CA of the user's certificate, or by a responder the issuing CA certified.
+//   - The status of the user's certificate is good.
+//   - The nonce starts with the SHA-1 hash of the base64 signature, which binds the response to the signature.
+//
+// Failures are returned as an OCSPResponderError, OCSPStatusError or OCSPNonceError.
+// BankID documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
+func VerifyOCSPResponse(c CompletionData) (*OCSPResponse, error) {
+	s, err := ParseSignature(c.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	user := s.UserCertificate()
+
+	issuer := issuerOf(s)
+	if issuer == nil {
+		return nil, OCSPResponderError{Err: fmt.Errorf("the issuer of the user's certificate is not in the signature")}
+	}
+
+	der, err := decodeBase64(c.OcspResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding ocsp response: %w", err)
+	}
+
+	// verifies the signature of the response and that a responder certificate is issued by the issuer
+	r, err := ocsp.ParseResponseForCert(der, user, issuer)
+	if err != nil {
+		return nil, OCSPResponderError{Err: err}
+	}
+
+	if r.Certificate != nil && !slices.Contains(r.Certificate.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+		return nil, OCSPResponderError{Err: fmt.Errorf("the responder certificate is not issued for OCSP signing")}
+	}
+
+	response, err := newOCSPResponse(der, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// the issuing CA signed the response itself
+	if response.Responder == nil && len(r.RawResponderName) == 0 {
+		response.ResponderName = issuer.Subject
+	}
+
+	if r.Status != ocsp.Good {
+		return nil, OCSPStatusError{Status: response.Status, RevokedAt: r.RevokedAt}
+	}
+
+	hash := sha1.Sum([]byte(c.Signature))
+	if !bytes.HasPrefix(response.Nonce, hash[:]) {
+		return nil, OCSPNonceError{}
+	}
+
+	return response, nil
+}
+
+// issuerOf returns the certificate of the signature that issued the user's certificate, or nil when it's missing.
+func issuerOf(s *BankIDSignature) *x509.Certificate {
+	for _, cert := range s.Certificates[1:] {
+		if s.UserCertificate().CheckSignatureFrom(cert) == nil {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+func newOCSPResponse(der []byte, r *ocsp.Response) (*OCSPResponse, error) {
+	response := &OCSPResponse{
+		Raw:          der,
+		SerialNumber: r.SerialNumber,
+		ProducedAt:   r.ProducedAt,
+		ThisUpdate:   r.ThisUpdate,
+		Responder:    r.Certificate,
+	}
+
+	switch r.Status {
+	case ocsp.Good:
+		response.Status = "good"
+	case ocsp.Revoked:
+		response.Status = "revoked"
+	default:
+		response.Status = "unknown"
+	}
+
+	if len(r.RawResponderName) > 0 {
+		var name pkix.RDNSequence
+		_, err := asn1.Unmarshal(r.RawResponderName, &name)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ocsp responder name: %w", err)
+		}
+		response.ResponderName.FillFromRDNSequence(&name)
+	} else if r.Certificate != nil {
+		response.ResponderName = r.Certificate.Subject
+	}
+
+	extensions, err := responseExtensions(der)
+	if err != nil {
+		return nil, err
+	}
+
+	// the nonce belongs in the response extensions, the extensions of the single response are a fallback
+	for _, ext := range append(extensions, r.Extensions...) {
+		if !ext.Id.Equal(oidOCSPNonce) {
+			continue
+		}
+
+		// the nonce is an OCTET STRING inside the extension value, some responders put the bytes there directly
+		var nonce []byte
+		rest, err := asn1.Unmarshal(ext.Value, &nonce)
+		if err != nil || len(rest) > 0 {
+			nonce = ext.Value
+		}
+		response.Nonce = nonce
+		break
+	}
+
+	return response, nil
+}
+
+// responseExtensions returns the response extensions of a DER encoded OCSP response.
+func responseExtensions(der []byte) ([]pkix.Extension, error) {
+	var resp ocspResponseASN1
+	_, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ocsp response: %w", err)
+	}
+
+	var basic basicOCSPResponseASN1
+	_, err = asn1.Unmarshal(resp.Response.Response, &basic)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing basic ocsp response: %w", err)
+	}
+
+	var data responseDataASN1
+	_, err = asn1.Unmarshal(basic.TBSResponseData.FullBytes, &data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ocsp response data: %w", err)
+	}
+
+	return data.Extensions, nil
+}
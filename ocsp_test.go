@@ -0,0 +1,191 @@
+package bankid
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspResponse returns an OCSP response for the user's certificate signed by the responder, with the nonce in the response extensions.
+func (p *testPKI) ocspResponse(t *testing.T, status int, nonce []byte, responder *x509.Certificate, responderKey crypto.Signer) string {
+	t.Helper()
+
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: p.user.SerialNumber,
+		ThisUpdate:   time.Now(),
+		RevokedAt:    time.Now().Add(-time.Hour),
+	}
+
+	// a delegated responder sends its certificate with the response
+	if responder != p.issuer {
+		template.Certificate = responder
+	}
+
+	der, err := ocsp.CreateResponse(p.issuer, responder, template, responderKey)
+	if err != nil {
+		t.Fatalf("error creating ocsp response: %v", err)
+	}
+
+	// ocsp.CreateResponse has no response extensions, add the nonce and sign the response data again
+	var resp ocspResponseASN1
+	var basic basicOCSPResponseASN1
+	var data responseDataASN1
+	_, err = asn1.Unmarshal(der, &resp)
+	if err == nil {
+		_, err = asn1.Unmarshal(resp.Response.Response, &basic)
+	}
+	if err == nil {
+		_, err = asn1.Unmarshal(basic.TBSResponseData.FullBytes, &data)
+	}
+	if err != nil {
+		t.Fatalf("error parsing ocsp response: %v", err)
+	}
+
+	value, _ := asn1.Marshal(nonce)
+	data.Extensions = append(data.Extensions, pkix.Extension{Id: oidOCSPNonce, Value: value})
+
+	tbs, err := asn1.Marshal(data)
+	if err != nil {
+		t.Fatalf("error marshalling response data: %v", err)
+	}
+
+	sum := sha256.Sum256(tbs)
+	signature, err := ecdsa.SignASN1(rand.Reader, responderKey.(*ecdsa.PrivateKey), sum[:])
+	if err != nil {
+		t.Fatalf("error signing response data: %v", err)
+	}
+
+	basic.TBSResponseData = asn1.RawValue{FullBytes: tbs}
+	basic.Signature = asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)}
+	resp.Response.Response, err = asn1.Marshal(basic)
+	if err != nil {
+		t.Fatalf("error marshalling basic response: %v", err)
+	}
+
+	der, err = asn1.Marshal(resp)
+	if err != nil {
+		t.Fatalf("error marshalling response: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+// responder returns a certificate issued by the bank's CA, with or without the OCSP signing usage.
+func (p *testPKI) responder(t *testing.T, ocspSigning bool) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "Test Bank OCSP Responder"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if ocspSigning {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, p.issuer, key.Public(), p.issuerKey)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %v", err)
+	}
+
+	return cert, key
+}
+
+func TestVerifyOCSPResponse(t *testing.T) {
+	pki := newTestPKI(t)
+	signature := base64.StdEncoding.EncodeToString([]byte(pki.signatureXML("Signing", "text", nil)))
+
+	hash := sha1.Sum([]byte(signature))
+	nonce := append(hash[:], make([]byte, 12)...)
+
+	c := CompletionData{
+		Signature:    signature,
+		OcspResponse: pki.ocspResponse(t, ocsp.Good, nonce, pki.issuer, pki.issuerKey),
+	}
+
+	response, err := VerifyOCSPResponse(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.Status != "good" || response.ResponderName.CommonName != "Test Bank Customer CA" || len(response.Nonce) != 32 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	if time.Since(response.ProducedAt) > 2*time.Minute || response.SerialNumber.Cmp(pki.user.SerialNumber) != 0 {
+		t.Errorf("unexpected produced at %v or serial number %v", response.ProducedAt, response.SerialNumber)
+	}
+
+	responder, responderKey := pki.responder(t, true)
+	c.OcspResponse = pki.ocspResponse(t, ocsp.Good, nonce, responder, responderKey)
+
+	response, err = VerifyOCSPResponse(c)
+	if err != nil {
+		t.Fatalf("unexpected error with a delegated responder: %v", err)
+	}
+
+	if response.Responder == nil || response.ResponderName.CommonName != "Test Bank OCSP Responder" {
+		t.Errorf("expected the delegated responder, got %+v", response.ResponderName)
+	}
+
+	parsed, err := ParseOCSPResponse(c.OcspResponse)
+	if err != nil || string(parsed.Nonce) != string(nonce) {
+		t.Errorf("expected the parsed nonce, got %x %v", parsed.Nonce, err)
+	}
+}
+
+func TestVerifyOCSPResponseFailures(t *testing.T) {
+	pki := newTestPKI(t)
+	signature := base64.StdEncoding.EncodeToString([]byte(pki.signatureXML("Signing", "text", nil)))
+
+	hash := sha1.Sum([]byte(signature))
+	nonce := append(hash[:], make([]byte, 12)...)
+
+	other := newTestPKI(t)
+	responder, responderKey := pki.responder(t, false)
+
+	tests := []struct {
+		name         string
+		ocspResponse string
+		wantErr      any
+	}{
+		{"other issuer", pki.ocspResponse(t, ocsp.Good, nonce, other.issuer, other.issuerKey), &OCSPResponderError{}},
+		{"responder without ocsp signing", pki.ocspResponse(t, ocsp.Good, nonce, responder, responderKey), &OCSPResponderError{}},
+		{"revoked", pki.ocspResponse(t, ocsp.Revoked, nonce, pki.issuer, pki.issuerKey), &OCSPStatusError{}},
+		{"unknown", pki.ocspResponse(t, ocsp.Unknown, nonce, pki.issuer, pki.issuerKey), &OCSPStatusError{}},
+		{"nonce of another signature", pki.ocspResponse(t, ocsp.Good, make([]byte, 32), pki.issuer, pki.issuerKey), &OCSPNonceError{}},
+	}
+
+	for _, tt := range tests {
+		_, err := VerifyOCSPResponse(CompletionData{Signature: signature, OcspResponse: tt.ocspResponse})
+		if err == nil || !errors.As(err, tt.wantErr) {
+			t.Errorf("%s: expected %T, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
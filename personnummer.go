@@ -0,0 +1,318 @@
+package bankid
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Gender is the legal gender of a person, as recorded in the personnummer.
+type Gender string
+
+const (
+	GenderFemale Gender = "female"
+	GenderMale   Gender = "male"
+)
+
+// IdentityNumberKind is the kind of a Swedish identity number.
+type IdentityNumberKind string
+
+const (
+	// The personal identity number of a person registered in Sweden.
+	KindPersonnummer IdentityNumberKind = "personnummer"
+
+	// The coordination number of a person who isn't registered in Sweden, the day of birth plus 60.
+	KindSamordningsnummer IdentityNumberKind = "samordningsnummer"
+
+	// The number of a legal entity, e.g. a company. The third digit is at least 2, a month in a personnummer is at most 12.
+	KindOrganisationsnummer IdentityNumberKind = "organisationsnummer"
+
+	// A reserve number of the healthcare regions for patients without a known personnummer. The format isn't standardised,
+	// they are recognised by letters in the birth number, e.g. YYYYMMDD-T123, or by 12 digits starting with 99.
+	KindReservnummer IdentityNumberKind = "reservnummer"
+
+	KindUnknown IdentityNumberKind = "unknown"
+)
+
+// ClassifyIdentityNumber returns the kind of an identity number by its format, the number is not validated.
+// Spaces and a - or + separator before the last 4 characters are ignored.
+func ClassifyIdentityNumber(s string) IdentityNumberKind {
+	s, _ = stripIdentityNumber(s)
+
+	if len(s) != 10 && len(s) != 12 {
+		return KindUnknown
+	}
+
+	if !isDigits(s[:len(s)-4]) {
+		return KindUnknown
+	}
+
+	if !isDigits(s[len(s)-4:]) {
+		return KindReservnummer
+	}
+
+	if len(s) == 12 {
+		switch {
+		case s[:2] == "99":
+			return KindReservnummer
+
+		// organisationsnummer are written with the prefix 16 in the 12 digit format
+		case s[:2] == "16" && s[4] >= '2':
+			return KindOrganisationsnummer
+		}
+
+		s = s[2:]
+	}
+
+	if s[2] >= '2' {
+		return KindOrganisationsnummer
+	}
+
+	if day := atoi(s[4:6]); day > 60 {
+		return KindSamordningsnummer
+	}
+
+	return KindPersonnummer
+}
+
+// Personnummer is a Swedish personal identity number, the personal number BankID identifies users by.
+// It's either a personnummer or a samordningsnummer, see Kind.
+// Parse it with ParsePersonnummer, String returns the 12 digit format BankID requires: YYYYMMDDNNNC.
+type Personnummer struct {
+	// the date of birth
+	birthDate time.Time
+
+	// the birth number NNN and the check digit C
+	serial string
+
+	// the day of birth is written plus 60
+	samordningsnummer bool
+}
+
+// ParsePersonnummer parses a personnummer or a samordningsnummer in one of the common formats:
+//   - 12 digits: YYYYMMDDNNNC or YYYYMMDD-NNNC
+//   - 10 digits: YYMMDDNNNC, YYMMDD-NNNC or YYMMDD+NNNC, where + is used once the person has turned 100
+//
+// Spaces are ignored. The century of the 10 digit formats is chosen from the current date.
+// Failures are returned as a PersonnummerError or SamordningsnummerError. An organisationsnummer or reservnummer
+// isn't the number of a BankID user and is rejected with an OrganisationsnummerError or ReservnummerError.
+func ParsePersonnummer(s string) (Personnummer, error) {
+	return parsePersonnummer(s, time.Now())
+}
+
+func parsePersonnummer(s string, now time.Time) (Personnummer, error) {
+	kind := ClassifyIdentityNumber(s)
+
+	invalid := func(reason string) error {
+		if kind == KindSamordningsnummer {
+			return SamordningsnummerError{Reason: reason}
+		}
+
+		return PersonnummerError{Reason: reason}
+	}
+
+	s, separator := stripIdentityNumber(s)
+	if s == "" {
+		return Personnummer{}, PersonnummerError{Reason: "received an empty string"}
+	}
+
+	switch kind {
+	case KindOrganisationsnummer:
+		return Personnummer{}, OrganisationsnummerError{Reason: "BankID identifies persons, not legal entities"}
+	case KindReservnummer:
+		return Personnummer{}, ReservnummerError{}
+	case KindUnknown:
+		if !isDigits(s) {
+			return Personnummer{}, invalid("it may only contain digits, spaces and a - or + before the last 4 digits")
+		}
+
+		return Personnummer{}, invalid(fmt.Sprintf("it has %d digits but 10 or 12 are expected", len(s)))
+	}
+
+	var year int
+	short := len(s) == 10
+
+	// the 12 digit format has the century, + only marks the age in the 10 digit format
+	if separator == '+' && !short {
+		return Personnummer{}, invalid("the + separator is only used in the 10 digit format")
+	}
+
+	if !short {
+		year = atoi(s[:4])
+		s = s[2:]
+	}
+
+	month, day := atoi(s[2:4]), atoi(s[4:6])
+	if kind == KindSamordningsnummer {
+		day -= 60
+	}
+
+	if short {
+		// the person is younger than 100, or at least 100 with the + separator
+		year = now.Year()/100*100 + atoi(s[:2])
+		if time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).After(now) {
+			year -= 100
+		}
+		if separator == '+' {
+			year -= 100
+		}
+	}
+
+	birthDate := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if birthDate.Month() != time.Month(month) || birthDate.Day() != day {
+		return Personnummer{}, invalid("the date of birth doesn't exist")
+	}
+
+	if birthDate.After(now) {
+		return Personnummer{}, invalid("the date of birth is in the future")
+	}
+
+	// the check digit is calculated over the 10 digit format
+	err := validateChecksum(s)
+	if err != nil {
+		return Personnummer{}, invalid("the check digit is wrong")
+	}
+
+	return Personnummer{birthDate: birthDate, serial: s[6:], samordningsnummer: kind == KindSamordningsnummer}, nil
+}
+
+// String returns the personnummer in the 12 digit format of BankID: YYYYMMDDNNNC.
+func (p Personnummer) String() string {
+	if p.serial == "" {
+		return ""
+	}
+
+	day := p.birthDate.Day()
+	if p.samordningsnummer {
+		day += 60
+	}
+
+	return fmt.Sprintf("%s%02d%s", p.birthDate.Format("200601"), day, p.serial)
+}
+
+// Kind returns KindPersonnummer or KindSamordningsnummer.
+func (p Personnummer) Kind() IdentityNumberKind {
+	if p.samordningsnummer {
+		return KindSamordningsnummer
+	}
+
+	return KindPersonnummer
+}
+
+// BirthDate returns the date of birth, at midnight UTC. The day of a samordningsnummer is the day of birth minus 60.
+func (p Personnummer) BirthDate() time.Time {
+	return p.birthDate
+}
+
+// Age returns the age of the person on the given date.
+func (p Personnummer) Age(at time.Time) int {
+	age := at.Year() - p.birthDate.Year()
+
+	// the birthday hasn't been reached yet that year
+	if at.Month() < p.birthDate.Month() || (at.Month() == p.birthDate.Month() && at.Day() < p.birthDate.Day()) {
+		age--
+	}
+
+	return age
+}
+
+// Gender returns the legal gender: the second to last digit is odd for men and even for women.
+func (p Personnummer) Gender() Gender {
+	if p.serial == "" {
+		return ""
+	}
+
+	if atoi(p.serial[2:3])%2 == 1 {
+		return GenderMale
+	}
+
+	return GenderFemale
+}
+
+// MarshalText encodes the personnummer in the 12 digit format, e.g. for JSON.
+func (p Personnummer) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText parses the personnummer in any of the formats of ParsePersonnummer.
+func (p *Personnummer) UnmarshalText(text []byte) error {
+	parsed, err := ParsePersonnummer(string(text))
+	if err != nil {
+		return err
+	}
+
+	*p = parsed
+	return nil
+}
+
+// Organisationsnummer is the number of a Swedish legal entity, e.g. a company or an association.
+// Parse it with ParseOrganisationsnummer, String returns the common format NNNNNN-NNNN.
+type Organisationsnummer struct {
+	number string
+}
+
+// ParseOrganisationsnummer parses an organisationsnummer: NNNNNNNNNN, NNNNNN-NNNN or the 12 digit format with the prefix 16.
+// Failures are returned as an OrganisationsnummerError.
+func ParseOrganisationsnummer(s string) (Organisationsnummer, error) {
+	if ClassifyIdentityNumber(s) != KindOrganisationsnummer {
+		return Organisationsnummer{}, OrganisationsnummerError{Reason: "the third digit of an organisationsnummer is at least 2"}
+	}
+
+	s, separator := stripIdentityNumber(s)
+	if separator == '+' {
+		return Organisationsnummer{}, OrganisationsnummerError{Reason: "the separator of an organisationsnummer is -"}
+	}
+
+	if len(s) == 12 {
+		s = s[2:]
+	}
+
+	err := validateChecksum(s)
+	if err != nil {
+		return Organisationsnummer{}, OrganisationsnummerError{Reason: "the check digit is wrong"}
+	}
+
+	return Organisationsnummer{number: s}, nil
+}
+
+// String returns the organisationsnummer in the format NNNNNN-NNNN.
+func (o Organisationsnummer) String() string {
+	if o.number == "" {
+		return ""
+	}
+
+	return o.number[:6] + "-" + o.number[6:]
+}
+
+// stripIdentityNumber removes spaces and the separator before the last 4 characters, the separator is returned.
+func stripIdentityNumber(s string) (string, byte) {
+	s = strings.Join(strings.Fields(s), "")
+
+	separator := byte('-')
+	if i := len(s) - 5; i >= 0 && (s[i] == '-' || s[i] == '+') {
+		separator = s[i]
+		s = s[:i] + s[i+1:]
+	}
+
+	return s, separator
+}
+
+func isDigits(s string) bool {
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// atoi converts a string of digits that was already validated.
+func atoi(s string) int {
+	n := 0
+	for _, ch := range s {
+		n = n*10 + int(ch-'0')
+	}
+
+	return n
+}
@@ -0,0 +1,236 @@
+package bankid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePersonnummer(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	formats := map[string]string{
+		"199510221287":    "199510221287",
+		"19951022-1287":   "199510221287",
+		"9510221287":      "199510221287",
+		"951022-1287":     "199510221287",
+		"951022 - 1287":   "199510221287",
+		" 1995 1022 1287": "199510221287",
+		"121212-1212":     "201212121212",
+		"121212+1212":     "191212121212",
+		"191212121212":    "191212121212",
+		"240601-2381":     "202406012381",
+	}
+
+	for input, expected := range formats {
+		p, err := parsePersonnummer(input, now)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", input, err)
+			continue
+		}
+
+		if p.String() != expected {
+			t.Errorf("expected %s for %q, got %s", expected, input, p.String())
+		}
+	}
+
+	invalid := []string{
+		"",
+		"199510221288",  // check digit
+		"19951322-1280", // month
+		"195102301234",  // day
+		"95102212",      // length
+		"9510221287-",   // separator position
+		"95102a1287",    // letters
+		"202501012383",  // the future
+		"19121212+1212", // + with the century
+	}
+
+	for _, input := range invalid {
+		_, err := parsePersonnummer(input, now)
+		if _, ok := err.(PersonnummerError); !ok {
+			t.Errorf("expected a PersonnummerError for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestValidateRequirementWithoutPersonalNumber(t *testing.T) {
+	for _, requirement := range []*Requirement{
+		{CertificatePolicies: []string{"1.2.752.78.1.5"}},
+		{CardReader: "class1"},
+	} {
+		if err := validate(validateRequirement(requirement)); err != nil {
+			t.Errorf("expected a requirement without a personal number to be valid, got %v", err)
+		}
+	}
+
+	err := validate(validateRequirement(&Requirement{PersonalNumber: "199510221288"}))
+	if !errors.As(err, &PersonnummerError{}) {
+		t.Errorf("expected the personal number of a requirement to be validated, got %v", err)
+	}
+}
+
+func TestValidatePersonalNumberInputInvalid(t *testing.T) {
+	tests := map[string]any{
+		"199510221288":  &PersonnummerError{},
+		"701063-2390":   &SamordningsnummerError{},
+		"556016-0680":   &OrganisationsnummerError{},
+		"19950101-T123": &ReservnummerError{},
+	}
+
+	for input, wantErr := range tests {
+		err := validate(validatePersonalNumber(input))
+		if !errors.As(err, wantErr) {
+			t.Errorf("expected %T for %q, got %v", wantErr, input, err)
+		}
+
+		// callers that check for invalid input find the errors of identity numbers too
+		var invalid InputInvalidError
+		if !errors.As(err, &invalid) || !errors.Is(err, InputInvalidError{}) {
+			t.Errorf("expected an InputInvalidError for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestClassifyIdentityNumber(t *testing.T) {
+	kinds := map[string]IdentityNumberKind{
+		"199510221287":  KindPersonnummer,
+		"951022-1287":   KindPersonnummer,
+		"701063-2391":   KindSamordningsnummer,
+		"19701063-2391": KindSamordningsnummer,
+		"556016-0680":   KindOrganisationsnummer,
+		"165560160680":  KindOrganisationsnummer,
+		"19950101-T123": KindReservnummer,
+		"991234567890":  KindReservnummer,
+		"95102212":      KindUnknown,
+		"abcdef-1234":   KindUnknown,
+	}
+
+	for input, expected := range kinds {
+		if kind := ClassifyIdentityNumber(input); kind != expected {
+			t.Errorf("expected %s for %q, got %s", expected, input, kind)
+		}
+	}
+}
+
+func TestIdentityNumberKinds(t *testing.T) {
+	p, err := ParsePersonnummer("701063-2391")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if p.Kind() != KindSamordningsnummer || p.String() != "197010632391" || !p.BirthDate().Equal(time.Date(1970, 10, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected samordningsnummer %s %s %v", p.Kind(), p, p.BirthDate())
+	}
+
+	tests := []struct {
+		input   string
+		wantErr error
+	}{
+		{"701093-2391", SamordningsnummerError{}},
+		{"701063-2392", SamordningsnummerError{}},
+		{"556016-0680", OrganisationsnummerError{}},
+		{"19950101-T123", ReservnummerError{}},
+	}
+
+	for _, tt := range tests {
+		_, err := ParsePersonnummer(tt.input)
+		if reflect.TypeOf(err) != reflect.TypeOf(tt.wantErr) {
+			t.Errorf("expected %T for %q, got %v", tt.wantErr, tt.input, err)
+		}
+	}
+
+	o, err := ParseOrganisationsnummer("16 5560160680")
+	if err != nil || o.String() != "556016-0680" {
+		t.Errorf("expected the organisationsnummer 556016-0680, got %s %v", o, err)
+	}
+
+	for _, input := range []string{"556016-0681", "199510221287", "556016+0680"} {
+		if _, err := ParseOrganisationsnummer(input); reflect.TypeOf(err) != reflect.TypeOf(OrganisationsnummerError{}) {
+			t.Errorf("expected an OrganisationsnummerError for %q, got %v", input, err)
+		}
+	}
+
+	// organisations can't identify themselves with BankID
+	b := &bankid{config: &RequestConfig{}}
+	_, err = b.PhoneAuth(context.Background(), PhoneAuthRequest{PersonalNumber: "556016-0680", CallInitiator: "user"})
+	if _, ok := err.(OrganisationsnummerError); !ok {
+		t.Errorf("expected an OrganisationsnummerError for a phone order, got %v", err)
+	}
+
+	_, err = b.Auth(context.Background(), AuthRequest{EndUserIP: "192.168.0.1", Requirement: &Requirement{PersonalNumber: "5560160680"}})
+	if _, ok := err.(OrganisationsnummerError); !ok {
+		t.Errorf("expected an OrganisationsnummerError for a requirement, got %v", err)
+	}
+}
+
+func TestPersonnummerAttributes(t *testing.T) {
+	p, err := ParsePersonnummer("19121212-1212")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !p.BirthDate().Equal(time.Date(1912, 12, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected birth date %v", p.BirthDate())
+	}
+
+	if p.Age(time.Date(2012, 12, 11, 0, 0, 0, 0, time.UTC)) != 99 || p.Age(time.Date(2012, 12, 12, 0, 0, 0, 0, time.UTC)) != 100 {
+		t.Errorf("expected the age to change on the birthday")
+	}
+
+	if p.Gender() != GenderMale {
+		t.Errorf("expected %s, got %s", GenderMale, p.Gender())
+	}
+
+	female, _ := ParsePersonnummer("199510221287")
+	if female.Gender() != GenderFemale {
+		t.Errorf("expected %s, got %s", GenderFemale, female.Gender())
+	}
+
+	var decoded struct {
+		PersonalNumber Personnummer `json:"personalNumber"`
+	}
+	err = json.Unmarshal([]byte(`{"personalNumber": "951022-1287"}`), &decoded)
+	if err != nil || decoded.PersonalNumber.String() != "199510221287" {
+		t.Errorf("expected the normalised personnummer from JSON, got %s %v", decoded.PersonalNumber, err)
+	}
+}
+
+func TestRequestPersonalNumberNormalised(t *testing.T) {
+	var sent AuthRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{"orderRef":"ref"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	b := &bankid{config: &RequestConfig{UrlBase: server.URL, Client: server.Client()}}
+
+	requirement := &Requirement{PersonalNumber: "951022-1287"}
+	_, err := b.Auth(context.Background(), AuthRequest{EndUserIP: "192.168.0.1", Requirement: requirement})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sent.Requirement == nil || sent.Requirement.PersonalNumber != "199510221287" {
+		t.Errorf("expected the 12 digit personal number to be sent, got %+v", sent.Requirement)
+	}
+
+	if requirement.PersonalNumber != "951022-1287" {
+		t.Errorf("expected the requirement of the caller unchanged, got %s", requirement.PersonalNumber)
+	}
+
+	req, err := process[PhoneAuthRequest](PhoneAuthRequest{PersonalNumber: "9510221287", Requirement: requirement}, processPersonalNumber())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.PersonalNumber != "199510221287" || req.Requirement.PersonalNumber != "199510221287" {
+		t.Errorf("expected normalised personal numbers, got %s and %s", req.PersonalNumber, req.Requirement.PersonalNumber)
+	}
+}
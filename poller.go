@@ -0,0 +1,437 @@
+package bankid
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOrderExpired is delivered to the subscribers of an order that is still pending after PollerConfig.MaxAge.
+	ErrOrderExpired = errors.New("order expired before it was completed")
+
+	// ErrPollerStopped is returned by Add once Run has returned, orders added then would never be polled.
+	ErrPollerStopped = errors.New("poller is stopped")
+)
+
+// PollerConfig configures a Poller
+type PollerConfig struct {
+	// Optional: The number of workers that call /collect concurrently.
+	// Default: 10
+	Workers int `json:"workers"`
+
+	// Optional: The maximum number of calls to /collect per second, shared by all orders.
+	// Default: 50
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+
+	// Optional: The interval between two calls to /collect for the same order.
+	// Default: 2s
+	Interval time.Duration `json:"interval"`
+
+	// Optional: Orders that are still pending after this duration are removed and their subscribers receive ErrOrderExpired.
+	// Default: 5m
+	MaxAge time.Duration `json:"maxAge"`
+}
+
+// Ensures the poller config is set based on the defaults or leaves the input unchanged if it's set
+func (c PollerConfig) UseDefault() PollerConfig {
+	if c.Workers == 0 {
+		c.Workers = 10
+	}
+
+	if c.RequestsPerSecond == 0 {
+		c.RequestsPerSecond = 50
+	}
+
+	if c.Interval == 0 {
+		c.Interval = collectInterval
+	}
+
+	if c.MaxAge == 0 {
+		c.MaxAge = 5 * time.Minute
+	}
+
+	return c
+}
+
+// PollEvent is delivered to the subscribers of an order when its status or hint code changes, or when collecting it failed.
+type PollEvent struct {
+	OrderRef string
+	Response *CollectResponse
+	Err      error
+}
+
+// Poller tracks many orders at once with a fixed number of workers.
+// Calls to /collect are scheduled on a shared timer wheel and limited to PollerConfig.RequestsPerSecond in total,
+// status changes are delivered to the subscribers of each order. Orders are removed when they are complete, failed,
+// cancelled, expired or when collecting them returned an error that isn't temporary.
+//
+// Example:
+//
+//	poller := bankid.NewPoller(b, bankid.PollerConfig{})
+//	go poller.Run(ctx)
+//
+//	events, err := poller.Add(authResponse.OrderRef)
+//
+//	for event := range events {
+//		// work with PollEvent, the channel is closed when the order is removed
+//	}
+type Poller struct {
+	client  BankID
+	config  PollerConfig
+	limiter *rateLimiter
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	orders map[string]*polledOrder
+	wheel  *timerWheel
+	queue  []*polledOrder
+	done   bool
+}
+
+type polledOrder struct {
+	orderRef    string
+	added       time.Time
+	last        *CollectResponse
+	subscribers []chan PollEvent
+}
+
+// NewPoller returns a Poller that collects orders with the client, call Run to start polling.
+func NewPoller(client BankID, config PollerConfig) *Poller {
+	config = config.UseDefault()
+
+	p := &Poller{
+		client:  client,
+		config:  config,
+		limiter: newRateLimiter(config.RequestsPerSecond),
+		orders:  map[string]*polledOrder{},
+		wheel:   newTimerWheel(wheelTick(config.Interval), 64),
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	return p
+}
+
+// Run starts the timer wheel and the workers and blocks until ctx is done.
+// When Run returns all orders are removed and their subscriptions closed.
+func (p *Poller) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for range p.config.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(p.wheel.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.mu.Lock()
+			p.done = true
+			p.cond.Broadcast()
+			p.mu.Unlock()
+
+			wg.Wait()
+
+			p.mu.Lock()
+			for orderRef := range p.orders {
+				p.remove(orderRef)
+			}
+			p.mu.Unlock()
+			return
+
+		case <-ticker.C:
+			p.mu.Lock()
+			due := p.wheel.advance()
+			if len(due) > 0 {
+				p.queue = append(p.queue, due...)
+				p.cond.Broadcast()
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// Add starts tracking the order and returns a subscription to it, see Subscribe. The first call to /collect is made on the next tick.
+// The subscription is created with the order, so the final status is never missed. Adding a tracked order only subscribes to it.
+// Orders can't be added once Run has returned, ErrPollerStopped is returned then.
+func (p *Poller) Add(orderRef string) (<-chan PollEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.done {
+		return nil, ErrPollerStopped
+	}
+
+	o, ok := p.orders[orderRef]
+	if !ok {
+		o = &polledOrder{orderRef: orderRef, added: time.Now()}
+		p.orders[orderRef] = o
+		p.wheel.schedule(o, 0)
+	}
+
+	return p.subscribe(o), nil
+}
+
+// Subscribe returns a channel that receives the status changes of a tracked order. The last known status is delivered right away.
+// The channel is closed when the order is removed, a slow subscriber only misses the oldest events.
+// An order that isn't tracked, e.g. because it was already removed, returns an OrderNotTrackedError.
+func (p *Poller) Subscribe(orderRef string) (<-chan PollEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	o, ok := p.orders[orderRef]
+	if !ok {
+		return nil, OrderNotTrackedError{OrderRef: orderRef}
+	}
+
+	return p.subscribe(o), nil
+}
+
+// subscribe must be called with p.mu held.
+func (p *Poller) subscribe(o *polledOrder) <-chan PollEvent {
+	ch := make(chan PollEvent, 8)
+	if o.last != nil {
+		ch <- PollEvent{OrderRef: o.orderRef, Response: o.last}
+	}
+
+	o.subscribers = append(o.subscribers, ch)
+	return ch
+}
+
+// Remove stops tracking the order and closes its subscriptions.
+func (p *Poller) Remove(orderRef string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.remove(orderRef)
+}
+
+// Cancel cancels the order at BankID and stops tracking it.
+func (p *Poller) Cancel(ctx context.Context, orderRef string) error {
+	_, err := p.client.Cancel(ctx, CancelRequest{OrderRef: orderRef})
+	if err != nil {
+		return err
+	}
+
+	p.Remove(orderRef)
+	return nil
+}
+
+// Len returns the number of tracked orders.
+func (p *Poller) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.orders)
+}
+
+// work collects the orders that are due until the poller is done.
+func (p *Poller) work(ctx context.Context) {
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.done {
+			p.cond.Wait()
+		}
+
+		if p.done {
+			p.mu.Unlock()
+			return
+		}
+
+		o := p.queue[0]
+		p.queue = p.queue[1:]
+		// the order may have been removed, or removed and added again, since it was scheduled
+		tracked := p.orders[o.orderRef] == o
+		p.mu.Unlock()
+
+		if !tracked {
+			continue
+		}
+
+		if err := p.limiter.wait(ctx); err != nil {
+			return
+		}
+
+		collectResponse, err := p.client.Collect(ctx, CollectRequest{OrderRef: o.orderRef})
+		if ctx.Err() != nil {
+			return
+		}
+
+		p.update(o, collectResponse, err)
+	}
+}
+
+// update delivers the result of a call to /collect and removes or reschedules the order.
+func (p *Poller) update(o *polledOrder, collectResponse *CollectResponse, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// removed while /collect was in flight
+	if p.orders[o.orderRef] != o {
+		return
+	}
+
+	orderRef := o.orderRef
+
+	if err != nil {
+		p.publish(o, PollEvent{OrderRef: orderRef, Err: err})
+
+		var t interface{ Temporary() bool }
+		if !errors.As(err, &t) || !t.Temporary() {
+			p.remove(orderRef)
+			return
+		}
+	} else {
+		if o.last == nil || o.last.Status != collectResponse.Status || o.last.HintCode != collectResponse.HintCode {
+			p.publish(o, PollEvent{OrderRef: orderRef, Response: collectResponse})
+		}
+		o.last = collectResponse
+
+		if collectResponse.Status != Pending {
+			p.remove(orderRef)
+			return
+		}
+	}
+
+	if time.Since(o.added) > p.config.MaxAge {
+		p.publish(o, PollEvent{OrderRef: orderRef, Err: ErrOrderExpired})
+		p.remove(orderRef)
+		return
+	}
+
+	p.wheel.schedule(o, p.config.Interval)
+}
+
+// publish delivers the event to every subscriber without blocking, the oldest event is dropped for subscribers that fall behind.
+func (p *Poller) publish(o *polledOrder, event PollEvent) {
+	for _, ch := range o.subscribers {
+		for sent := false; !sent; {
+			select {
+			case ch <- event:
+				sent = true
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// remove must be called with p.mu held.
+func (p *Poller) remove(orderRef string) {
+	o, ok := p.orders[orderRef]
+	if !ok {
+		return
+	}
+
+	for _, ch := range o.subscribers {
+		close(ch)
+	}
+
+	delete(p.orders, orderRef)
+}
+
+// timerWheel is a hashed timing wheel, each slot holds the orders that are due when the cursor reaches it.
+// Orders that are due further away than one rotation wait for the remaining number of rounds.
+type timerWheel struct {
+	tick   time.Duration
+	slots  [][]wheelEntry
+	cursor int
+}
+
+type wheelEntry struct {
+	order  *polledOrder
+	rounds int
+}
+
+func newTimerWheel(tick time.Duration, size int) *timerWheel {
+	return &timerWheel{
+		tick:  tick,
+		slots: make([][]wheelEntry, size),
+	}
+}
+
+// wheelTick returns the resolution of the timer wheel for the polling interval.
+func wheelTick(interval time.Duration) time.Duration {
+	return min(max(interval/20, time.Millisecond), 100*time.Millisecond)
+}
+
+// schedule adds the order to the slot that is reached after the duration, rounded up to at least one tick.
+func (w *timerWheel) schedule(o *polledOrder, after time.Duration) {
+	ticks := max(int(after/w.tick), 1)
+	slot := (w.cursor + ticks) % len(w.slots)
+
+	w.slots[slot] = append(w.slots[slot], wheelEntry{
+		order:  o,
+		rounds: (ticks - 1) / len(w.slots),
+	})
+}
+
+// advance moves the cursor one tick and returns the orders that are due.
+func (w *timerWheel) advance() []*polledOrder {
+	w.cursor = (w.cursor + 1) % len(w.slots)
+
+	var due []*polledOrder
+	pending := w.slots[w.cursor][:0]
+	for _, e := range w.slots[w.cursor] {
+		if e.rounds == 0 {
+			due = append(due, e.order)
+			continue
+		}
+
+		e.rounds--
+		pending = append(pending, e)
+	}
+	w.slots[w.cursor] = pending
+
+	return due
+}
+
+// rateLimiter spaces out calls evenly so that no more than the given number of calls are made per second.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / perSecond),
+	}
+}
+
+// wait blocks until the next call is allowed or ctx is done.
+func (r *rateLimiter) wait(ctx context.Context) error {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	at := r.next
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
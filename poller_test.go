@@ -0,0 +1,134 @@
+package bankid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoller(t *testing.T) {
+	responses := map[string][]string{
+		"a": {
+			`{"orderRef":"a","status":"pending","hintCode":"outstandingTransaction"}`,
+			`{"orderRef":"a","status":"pending","hintCode":"outstandingTransaction"}`,
+			`{"orderRef":"a","status":"pending","hintCode":"userSign"}`,
+			`{"orderRef":"a","status":"complete"}`,
+		},
+		"b": {
+			`{"orderRef":"b","status":"pending","hintCode":"outstandingTransaction"}`,
+			`{"orderRef":"b","status":"failed","hintCode":"userCancel"}`,
+		},
+	}
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CollectRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		mu.Lock()
+		i := min(calls[req.OrderRef], len(responses[req.OrderRef])-1)
+		calls[req.OrderRef]++
+		mu.Unlock()
+
+		w.Write([]byte(responses[req.OrderRef][i]))
+	}))
+	defer server.Close()
+
+	b := &bankid{config: &RequestConfig{UrlBase: server.URL, Client: server.Client()}}
+	poller := NewPoller(b, PollerConfig{Workers: 2, Interval: 5 * time.Millisecond, RequestsPerSecond: 1000})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		poller.Run(ctx)
+		close(stopped)
+	}()
+
+	expected := map[string][]HintCode{
+		"a": {OutstandingTransaction, UserSign, ""},
+		"b": {OutstandingTransaction, UserCancel},
+	}
+
+	var wg sync.WaitGroup
+	for orderRef, hintCodes := range expected {
+		events, err := poller.Add(orderRef)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var received []HintCode
+			for event := range events {
+				if event.Err != nil {
+					t.Errorf("unexpected error for %s: %v", orderRef, event.Err)
+					continue
+				}
+				received = append(received, event.Response.HintCode)
+			}
+
+			if len(received) != len(hintCodes) {
+				t.Errorf("expected %v for %s, got %v", hintCodes, orderRef, received)
+				return
+			}
+			for i := range hintCodes {
+				if received[i] != hintCodes[i] {
+					t.Errorf("expected %v for %s, got %v", hintCodes, orderRef, received)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if poller.Len() != 0 {
+		t.Errorf("expected all orders to be removed, %d left", poller.Len())
+	}
+
+	if _, err := poller.Subscribe("a"); !errors.As(err, &OrderNotTrackedError{}) {
+		t.Errorf("expected an OrderNotTrackedError when subscribing to a removed order, got %v", err)
+	}
+
+	cancel()
+	<-stopped
+
+	if _, err := poller.Add("c"); !errors.Is(err, ErrPollerStopped) {
+		t.Errorf("expected ErrPollerStopped after Run returned, got %v", err)
+	}
+}
+
+func TestTimerWheel(t *testing.T) {
+	soon, later := &polledOrder{orderRef: "soon"}, &polledOrder{orderRef: "later"}
+
+	w := newTimerWheel(time.Millisecond, 4)
+	w.schedule(soon, 2*time.Millisecond)
+	w.schedule(later, 9*time.Millisecond)
+
+	for tick := 1; tick <= 9; tick++ {
+		due := w.advance()
+
+		switch tick {
+		case 2:
+			if len(due) != 1 || due[0] != soon {
+				t.Errorf("expected soon at tick 2, got %v", due)
+			}
+		case 9:
+			if len(due) != 1 || due[0] != later {
+				t.Errorf("expected later at tick 9, got %v", due)
+			}
+		default:
+			if len(due) != 0 {
+				t.Errorf("expected nothing at tick %d, got %v", tick, due)
+			}
+		}
+	}
+}
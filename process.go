@@ -16,7 +16,7 @@ func process[T RequestBody](request T, opts ...ProcessOption) (T, error) {
 			request = val.(T)
 			continue
 		default:
-			fmt.Printf("Warning: %v\n", err)
+			fmt.Printf("Warning: %s\n", redactText(err.Error()))
 		}
 	}
 
@@ -53,6 +53,10 @@ func processUserVisibleData(userVisibleData string) ProcessOption {
 		case PhoneSignRequest:
 			v.UserVisibleData = userVisibleData
 			return v, nil
+
+		case PaymentRequest:
+			v.UserVisibleData = userVisibleData
+			return v, nil
 		}
 
 		return rb, nil
@@ -89,6 +93,10 @@ func processUserNonVisibleData(userNonVisibleData string) ProcessOption {
 		case PhoneSignRequest:
 			v.UserNonVisibleData = userNonVisibleData
 			return v, nil
+
+		case PaymentRequest:
+			v.UserNonVisibleData = userNonVisibleData
+			return v, nil
 		}
 
 		return rb, nil
@@ -120,6 +128,71 @@ func processUserVisibleDataFormat(userVisibleDataFormat string) ProcessOption {
 		case PhoneSignRequest:
 			v.UserVisibleDataFormat = userVisibleDataFormat
 			return v, nil
+
+		case PaymentRequest:
+			v.UserVisibleDataFormat = userVisibleDataFormat
+			return v, nil
+		}
+
+		return rb, nil
+	}
+}
+
+// Ensures the personal numbers of the request are in the 12 digit format BankID requires, YYYYMMDDNNNC
+func processPersonalNumber() ProcessOption {
+	return func(rb RequestBody) (RequestBody, error) {
+		normalise := func(personalNumber string) (string, error) {
+			if personalNumber == "" {
+				return "", nil
+			}
+
+			p, err := ParsePersonnummer(personalNumber)
+			if err != nil {
+				return personalNumber, err
+			}
+
+			return p.String(), nil
+		}
+
+		// the requirement is copied, the request of the caller is left unchanged
+		requirement := func(r *Requirement) (*Requirement, error) {
+			if r == nil {
+				return nil, nil
+			}
+
+			c := *r
+			var err error
+			c.PersonalNumber, err = normalise(r.PersonalNumber)
+			return &c, err
+		}
+
+		var err error
+		switch v := (rb).(type) {
+		case AuthRequest:
+			v.Requirement, err = requirement(v.Requirement)
+			return v, err
+
+		case SignRequest:
+			v.Requirement, err = requirement(v.Requirement)
+			return v, err
+
+		case PhoneAuthRequest:
+			v.Requirement, err = requirement(v.Requirement)
+			if err == nil {
+				v.PersonalNumber, err = normalise(v.PersonalNumber)
+			}
+			return v, err
+
+		case PhoneSignRequest:
+			v.Requirement, err = requirement(v.Requirement)
+			if err == nil {
+				v.PersonalNumber, err = normalise(v.PersonalNumber)
+			}
+			return v, err
+
+		case PaymentRequest:
+			v.Requirement, err = requirement(v.Requirement)
+			return v, err
 		}
 
 		return rb, nil
@@ -0,0 +1,138 @@
+package bankid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// The minimum length of the secret of a PseudonymKey, the size of the SHA-256 hash.
+const minPseudonymSecret = 32
+
+// PseudonymKey is a secret key of a Pseudonymizer.
+type PseudonymKey struct {
+	// Required: The version of the key, e.g. "v2". It's the prefix of the pseudonyms of the key and may not contain a dot.
+	Version string
+
+	// Required: The secret of the key, at least 32 random bytes. Keep it out of the database that stores the pseudonyms.
+	Secret []byte
+}
+
+// Pseudonymizer derives stable pseudonyms of users from completed orders, so services can recognise users without storing their personal numbers.
+// A pseudonym is the HMAC-SHA256 of the user's personal number in the 12 digit format, prefixed with the version of the key: "v2.9f86d0...".
+//
+// Keys are rotated by adding a new key first, the pseudonyms of the older keys still match during the migration window.
+//
+// Example:
+//
+//	p := bankid.Pseudonymizer{
+//		Namespace: "shop.example.com",
+//		Keys:      []bankid.PseudonymKey{{Version: "v2", Secret: newSecret}, {Version: "v1", Secret: oldSecret}},
+//	}
+//
+//	// look the user up by the pseudonyms of every key, and store the pseudonym of the current key
+//	pseudonyms, err := p.Pseudonyms(collectResponse.CompletionData)
+type Pseudonymizer struct {
+	// Optional: The namespace of the RP. The same personal number has unrelated pseudonyms in different namespaces,
+	// even when the RPs share a key.
+	// Default: ""
+	Namespace string
+
+	// Required: The keys of the pseudonyms. The first key is the current key, the pseudonyms of the others are still accepted.
+	Keys []PseudonymKey
+}
+
+// Pseudonym returns the pseudonym of the user of a completed order with the current key.
+func (p Pseudonymizer) Pseudonym(c CompletionData) (string, error) {
+	pseudonyms, err := p.Pseudonyms(c)
+	if err != nil {
+		return "", err
+	}
+
+	return pseudonyms[0], nil
+}
+
+// Pseudonyms returns the pseudonyms of the user of a completed order with every key, starting with the current key.
+func (p Pseudonymizer) Pseudonyms(c CompletionData) ([]string, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	personnummer, err := ParsePersonnummer(c.User.PersonalNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	pseudonyms := make([]string, len(p.Keys))
+	for i, key := range p.Keys {
+		pseudonyms[i] = p.pseudonym(key, personnummer)
+	}
+
+	return pseudonyms, nil
+}
+
+// Match reports whether a stored pseudonym belongs to the user of a completed order. Only the key of the pseudonym's
+// version is used, a pseudonym of a version without a key doesn't match.
+// current reports whether the pseudonym is of the current key, a match of an older key should be replaced
+// with the pseudonym of the current key.
+func (p Pseudonymizer) Match(c CompletionData, pseudonym string) (matched bool, current bool, err error) {
+	err = p.validate()
+	if err != nil {
+		return false, false, err
+	}
+
+	version, _, ok := strings.Cut(pseudonym, ".")
+	if !ok {
+		return false, false, InputInvalidError{Message: "Pseudonym has no version prefix"}
+	}
+
+	personnummer, err := ParsePersonnummer(c.User.PersonalNumber)
+	if err != nil {
+		return false, false, err
+	}
+
+	for i, key := range p.Keys {
+		if key.Version == version {
+			matched = hmac.Equal([]byte(p.pseudonym(key, personnummer)), []byte(pseudonym))
+			return matched, matched && i == 0, nil
+		}
+	}
+
+	return false, false, nil
+}
+
+func (p Pseudonymizer) pseudonym(key PseudonymKey, personnummer Personnummer) string {
+	mac := hmac.New(sha256.New, key.Secret)
+
+	// the namespace is separated from the personal number, so no namespace can be extended into another one
+	fmt.Fprintf(mac, "bankid-pseudonym\x00%s\x00%s", p.Namespace, personnummer.String())
+
+	return key.Version + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p Pseudonymizer) validate() error {
+	if len(p.Keys) == 0 {
+		return RequiredInputMissingError{Message: "Keys are missing but required to derive pseudonyms"}
+	}
+
+	versions := map[string]bool{}
+	for _, key := range p.Keys {
+		if key.Version == "" || strings.Contains(key.Version, ".") {
+			return InputInvalidError{Message: fmt.Sprintf("Pseudonym key version: %q is invalid, it should be a non-empty string without dots", key.Version)}
+		}
+
+		if versions[key.Version] {
+			return InputInvalidError{Message: fmt.Sprintf("Pseudonym key version: %s is used by more than one key", key.Version)}
+		}
+		versions[key.Version] = true
+
+		if len(key.Secret) < minPseudonymSecret {
+			return InputInvalidError{Message: fmt.Sprintf("Pseudonym key %s has a secret of %d bytes, at least %d are required", key.Version, len(key.Secret), minPseudonymSecret)}
+		}
+	}
+
+	return nil
+}
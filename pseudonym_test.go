@@ -0,0 +1,91 @@
+package bankid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPseudonymizer(t *testing.T) {
+	v1 := PseudonymKey{Version: "v1", Secret: bytes.Repeat([]byte{1}, 32)}
+	v2 := PseudonymKey{Version: "v2", Secret: bytes.Repeat([]byte{2}, 32)}
+
+	p := Pseudonymizer{Namespace: "shop.example.com", Keys: []PseudonymKey{v1}}
+	c := CompletionData{User: User{PersonalNumber: "199510221287"}}
+
+	old, err := p.Pseudonym(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(old, "v1.") || len(old) != len("v1.")+64 || strings.Contains(old, "199510221287") {
+		t.Errorf("unexpected pseudonym %s", old)
+	}
+
+	// the same user in another format of the personal number
+	same, _ := p.Pseudonym(CompletionData{User: User{PersonalNumber: "951022-1287"}})
+	if same != old {
+		t.Errorf("expected the pseudonym of the normalised personal number, got %s and %s", old, same)
+	}
+
+	// the key is rotated, the old pseudonym still matches
+	p.Keys = []PseudonymKey{v2, v1}
+
+	current, err := p.Pseudonym(c)
+	if err != nil || !strings.HasPrefix(current, "v2.") || current == old {
+		t.Errorf("expected a pseudonym of the new key, got %s %v", current, err)
+	}
+
+	pseudonyms, _ := p.Pseudonyms(c)
+	if len(pseudonyms) != 2 || pseudonyms[0] != current || pseudonyms[1] != old {
+		t.Errorf("expected the pseudonyms of both keys, got %v", pseudonyms)
+	}
+
+	if ok, isCurrent, err := p.Match(c, old); !ok || isCurrent || err != nil {
+		t.Errorf("expected the old pseudonym to match with an older key, got %t %t %v", ok, isCurrent, err)
+	}
+
+	if ok, isCurrent, err := p.Match(c, current); !ok || !isCurrent || err != nil {
+		t.Errorf("expected the pseudonym of the current key to match, got %t %t %v", ok, isCurrent, err)
+	}
+
+	if ok, _, _ := p.Match(CompletionData{User: User{PersonalNumber: "191212121212"}}, old); ok {
+		t.Errorf("expected the pseudonym of another user not to match")
+	}
+
+	// the hash of the old key doesn't match under the version of the current key
+	if ok, _, _ := p.Match(c, "v2"+strings.TrimPrefix(old, "v1")); ok {
+		t.Errorf("expected a pseudonym to only match with the key of its version")
+	}
+
+	if ok, _, err := p.Match(c, "v3"+strings.TrimPrefix(current, "v2")); ok || err != nil {
+		t.Errorf("expected a pseudonym of an unknown version not to match, got %t %v", ok, err)
+	}
+
+	if _, _, err := p.Match(c, strings.TrimPrefix(current, "v2.")); err == nil {
+		t.Errorf("expected an error for a pseudonym without a version")
+	}
+
+	// another RP with the same key
+	other, _ := Pseudonymizer{Namespace: "bank.example.com", Keys: []PseudonymKey{v2}}.Pseudonym(c)
+	if other == current {
+		t.Errorf("expected unrelated pseudonyms in different namespaces")
+	}
+
+	invalid := []Pseudonymizer{
+		{},
+		{Keys: []PseudonymKey{{Version: "v1", Secret: []byte("short")}}},
+		{Keys: []PseudonymKey{{Version: "v.1", Secret: v1.Secret}}},
+		{Keys: []PseudonymKey{v1, v1}},
+	}
+
+	for _, p := range invalid {
+		if _, err := p.Pseudonym(c); err == nil {
+			t.Errorf("expected an error for %+v", p.Keys)
+		}
+	}
+
+	if _, err := p.Pseudonym(CompletionData{}); err == nil {
+		t.Errorf("expected an error without a personal number")
+	}
+}
@@ -0,0 +1,146 @@
+package bankid
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+)
+
+// ErrQRCodeExpired is returned by a QRSession after the validity window of the animated QR code has passed.
+var ErrQRCodeExpired = errors.New("qr code expired, start a new order")
+
+// The animated QR code is valid for 30 seconds after the order was started, the order fails with `startFailed` when it isn't scanned in time.
+const qrValidity = 30 * time.Second
+
+// Clock tells the time to a QRSession, it can be replaced to test code that uses QR codes deterministically.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// QRStarter is implemented by the responses of orders that can be started with an animated QR code.
+type QRStarter interface {
+	qrStart() (qrStartToken string, qrStartSecret string)
+}
+
+func (r AuthResponse) qrStart() (string, string) {
+	return r.QrStartToken, r.QrStartSecret
+}
+
+func (r SignResponse) qrStart() (string, string) {
+	return r.QrStartToken, r.QrStartSecret
+}
+
+func (r PaymentResponse) qrStart() (string, string) {
+	return r.QrStartToken, r.QrStartSecret
+}
+
+// QROption configures a QRSession
+type QROption func(*QRSession)
+
+// WithClock replaces the system clock used by the session.
+func WithClock(clock Clock) QROption {
+	return func(s *QRSession) {
+		s.clock = clock
+	}
+}
+
+// WithValidity replaces the 30 second window in which the QR code can be scanned.
+func WithValidity(validity time.Duration) QROption {
+	return func(s *QRSession) {
+		s.validity = validity
+	}
+}
+
+// QRSession generates the payloads of the animated QR code of an order.
+// The start of the order is recorded when the session is created, so it should be created as soon as the order response is received.
+//
+// Example:
+//
+//	session := bankid.NewQRSession(authResponse)
+//
+//	for payload, err := range session.Payloads(ctx) {
+//		if err != nil {
+//			// bankid.ErrQRCodeExpired or ctx.Err()
+//			return err
+//		}
+//		// render the payload as a QR code
+//	}
+type QRSession struct {
+	qrStartToken  string
+	qrStartSecret string
+	start         time.Time
+	validity      time.Duration
+	clock         Clock
+}
+
+// NewQRSession starts the animated QR code for the order of an AuthResponse, a SignResponse or a PaymentResponse.
+func NewQRSession(order QRStarter, opts ...QROption) *QRSession {
+	s := &QRSession{
+		validity: qrValidity,
+		clock:    systemClock{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.qrStartToken, s.qrStartSecret = order.qrStart()
+	s.start = s.clock.Now()
+
+	return s
+}
+
+// Elapsed returns the time since the order was started.
+func (s *QRSession) Elapsed() time.Duration {
+	return s.clock.Now().Sub(s.start)
+}
+
+// Expired reports whether the validity window of the QR code has passed.
+func (s *QRSession) Expired() bool {
+	return s.Elapsed() > s.validity
+}
+
+// Current returns the payload of the QR code for the current second, or ErrQRCodeExpired.
+func (s *QRSession) Current() (string, error) {
+	elapsed := s.Elapsed()
+	if elapsed > s.validity {
+		return "", ErrQRCodeExpired
+	}
+
+	return GenerateQrPayload(s.qrStartSecret, s.qrStartToken, int(elapsed/time.Second))
+}
+
+// Payloads yields the current payload right away and a new payload at the start of every following second.
+// The sequence ends with ErrQRCodeExpired after the validity window, or with ctx.Err() when ctx is done.
+func (s *QRSession) Payloads(ctx context.Context) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for {
+			payload, err := s.Current()
+			if !yield(payload, err) || err != nil {
+				return
+			}
+
+			// wait until the next full second since the start of the order
+			wait := time.Second - s.Elapsed()%time.Second
+
+			select {
+			case <-ctx.Done():
+				yield("", ctx.Err())
+				return
+			case <-s.clock.After(wait):
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package bankid
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock moves forward when it's waited on
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestQRSession(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	order := AuthResponse{
+		QrStartToken:  "67df3917-fa0d-44e5-b327-edcc928297f8",
+		QrStartSecret: "d28db9a7-4cde-429e-a983-359be676944c",
+	}
+
+	session := NewQRSession(order, WithClock(clock))
+
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	payload, err := session.Current()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected, _ := GenerateQrPayload(order.QrStartSecret, order.QrStartToken, 1)
+	if payload != expected {
+		t.Errorf("expected %s, got %s", expected, payload)
+	}
+
+	var payloads []string
+	for payload, err = range session.Payloads(context.Background()) {
+		if err != nil {
+			break
+		}
+		payloads = append(payloads, payload)
+	}
+
+	if !errors.Is(err, ErrQRCodeExpired) {
+		t.Errorf("expected the session to expire, got %v", err)
+	}
+
+	// seconds 1 to 30
+	if len(payloads) != 30 {
+		t.Errorf("expected 30 payloads, got %d", len(payloads))
+	}
+
+	last, _ := GenerateQrPayload(order.QrStartSecret, order.QrStartToken, 30)
+	if payloads[len(payloads)-1] != last {
+		t.Errorf("expected the last payload to be for second 30, got %s", payloads[len(payloads)-1])
+	}
+}
@@ -0,0 +1,263 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// the "HELLO WORLD" 1-M example from https://www.thonky.com/qr-code-tutorial/error-correction-coding
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	ecc := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	if !bytes.Equal(ecc, expected) {
+		t.Errorf("expected %v, got %v", expected, ecc)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	for _, tt := range []struct {
+		level    Level
+		mask     int
+		expected int
+	}{
+		{Low, 4, 0b110011000101111},
+		{Medium, 0, 0b101010000010010},
+		{Quartile, 7, 0b010101111101101},
+		{High, 2, 0b001110011100111},
+	} {
+		if bits := formatBits(tt.level, tt.mask); bits != tt.expected {
+			t.Errorf("level %d mask %d: expected %015b, got %015b", tt.level, tt.mask, tt.expected, bits)
+		}
+	}
+
+	c := &Code{version: 7, size: 45}
+	c.modules = make([][]bool, c.size)
+	c.function = make([][]bool, c.size)
+	for i := range c.size {
+		c.modules[i] = make([]bool, c.size)
+		c.function[i] = make([]bool, c.size)
+	}
+	c.drawVersion()
+
+	// version 7 is 000111110010010100, the least significant bit is drawn first
+	var bits int
+	for i := 17; i >= 0; i-- {
+		bits <<= 1
+		if c.modules[i/3][c.size-11+i%3] {
+			bits |= 1
+		}
+	}
+	if bits != 0b000111110010010100 {
+		t.Errorf("unexpected version bits %018b", bits)
+	}
+}
+
+// decode reads the byte mode segment back from the code, checking the error correction of every block.
+func decode(t *testing.T, c *Code, level Level) string {
+	t.Helper()
+
+	// find the mask from the format bits next to the top left finder pattern
+	var bits int
+	for i := 14; i >= 9; i-- {
+		bits = bits<<1 | b2i(c.modules[8][14-i])
+	}
+	bits = bits<<1 | b2i(c.modules[8][7])
+	bits = bits<<1 | b2i(c.modules[8][8])
+	bits = bits<<1 | b2i(c.modules[7][8])
+	for i := 5; i >= 0; i-- {
+		bits = bits<<1 | b2i(c.modules[i][8])
+	}
+
+	mask := -1
+	for m := range 8 {
+		if formatBits(level, m) == bits {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("format bits %015b don't match level %d", bits, level)
+	}
+
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+
+	var codewords []byte
+	var current, n int
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.size {
+			for j := range 2 {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if c.function[y][x] {
+					continue
+				}
+				current = current<<1 | b2i(c.modules[y][x])
+				if n++; n%8 == 0 {
+					codewords = append(codewords, byte(current))
+					current = 0
+				}
+			}
+		}
+	}
+
+	numBlocks := errorCorrectionBlocks[level][c.version]
+	eccLen := errorCorrectionCodewordsPerBlock[level][c.version]
+	raw := rawDataModules(c.version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortData := raw/numBlocks - eccLen
+
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := range shortData + 1 {
+		for j := range blocks {
+			if i < shortData || j >= numShort {
+				blocks[j] = append(blocks[j], codewords[k])
+				k++
+			}
+		}
+	}
+	for range eccLen {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], codewords[k])
+			k++
+		}
+	}
+
+	var data []byte
+	for _, block := range blocks {
+		n := len(block) - eccLen
+		if !bytes.Equal(reedSolomonRemainder(block[:n], reedSolomonDivisor(eccLen)), block[n:]) {
+			t.Fatalf("error correction of block doesn't match")
+		}
+		data = append(data, block[:n]...)
+	}
+
+	var bitsRead []bool
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			bitsRead = append(bitsRead, (b>>i)&1 == 1)
+		}
+	}
+	read := func(n int) int {
+		v := 0
+		for range n {
+			v = v<<1 | b2i(bitsRead[0])
+			bitsRead = bitsRead[1:]
+		}
+		return v
+	}
+
+	if mode := read(4); mode != 0b0100 {
+		t.Fatalf("expected byte mode, got %04b", mode)
+	}
+
+	length := read(countBits(c.version))
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = byte(read(8))
+	}
+
+	return string(text)
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEncode(t *testing.T) {
+	payload := "bankid.67df3917-fa0d-44e5-b327-edcc928297f8.12.dc69358e712458a66a7525beef148ae8526b1c71610eff2c16cdffb4cdac9bf8"
+
+	for _, level := range []Level{Low, Medium, Quartile, High} {
+		c, err := Encode(payload, level)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if c.Size() != c.Version()*4+17 {
+			t.Errorf("unexpected size %d for version %d", c.Size(), c.Version())
+		}
+
+		if text := decode(t, c, level); text != payload {
+			t.Errorf("level %d: expected %s, got %s", level, payload, text)
+		}
+	}
+
+	long := strings.Repeat("bankid", 300)
+	c, err := Encode(long, Low)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Version() < 10 || decode(t, c, Low) != long {
+		t.Errorf("expected a large version to round trip, got version %d", c.Version())
+	}
+
+	if _, err := Encode(strings.Repeat("x", 3000), Low); err == nil {
+		t.Errorf("expected an error for text that doesn't fit")
+	}
+}
+
+func TestRender(t *testing.T) {
+	payload := "bankid.67df3917-fa0d-44e5-b327-edcc928297f8.0.dc69358e712458a66a7525beef148ae8526b1c71610eff2c16cdffb4cdac9bf8"
+
+	b, err := PNG(payload, WithScale(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("invalid png: %v", err)
+	}
+
+	c, _ := Encode(payload, Low)
+	if img.Bounds().Dx() != (c.Size()+8)*2 {
+		t.Errorf("unexpected image width %d", img.Bounds().Dx())
+	}
+
+	svg, err := SVG(payload)
+	if err != nil || !strings.HasPrefix(svg, "<svg") {
+		t.Errorf("unexpected svg: %v", err)
+	}
+
+	terminal, err := Terminal(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Count(terminal, "\n"); lines != (c.Size()+8+1)/2 {
+		t.Errorf("unexpected number of terminal lines %d", lines)
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	for version, expected := range map[int][]int{
+		1:  nil,
+		2:  {6, 18},
+		7:  {6, 22, 38},
+		15: {6, 26, 48, 70},
+		32: {6, 34, 60, 86, 112, 138},
+		40: {6, 30, 58, 86, 114, 142, 170},
+	} {
+		positions := alignmentPositions(version)
+		if len(positions) != len(expected) {
+			t.Errorf("version %d: expected %v, got %v", version, expected, positions)
+			continue
+		}
+		for i := range expected {
+			if positions[i] != expected[i] {
+				t.Errorf("version %d: expected %v, got %v", version, expected, positions)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strings"
+
+	"github.com/nicolaa5/bankid"
+)
+
+// Option configures how a QR code is encoded and rendered
+type Option func(*options)
+
+type options struct {
+	level    Level
+	scale    int
+	border   int
+	inverted bool
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		level:  Low,
+		scale:  8,
+		border: 4,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithLevel sets the error correction level.
+// Default: Low
+func WithLevel(level Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithScale sets the number of pixels of each module in PNG images.
+// Default: 8, the BankID payload results in a PNG of about 400x400 pixels
+func WithScale(scale int) Option {
+	return func(o *options) {
+		o.scale = max(scale, 1)
+	}
+}
+
+// WithBorder sets the width of the light quiet zone around the code in modules, scanners need at least 4.
+// Default: 4
+func WithBorder(border int) Option {
+	return func(o *options) {
+		o.border = max(border, 0)
+	}
+}
+
+// WithInverted swaps dark and light in terminal output, use it for terminals with a light background.
+func WithInverted() Option {
+	return func(o *options) {
+		o.inverted = true
+	}
+}
+
+// Image returns the QR code as a black and white image.
+func (c *Code) Image(opts ...Option) image.Image {
+	o := newOptions(opts)
+	size := (c.size + 2*o.border) * o.scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := range size {
+		for x := range size {
+			if c.Dark(x/o.scale-o.border, y/o.scale-o.border) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	return img
+}
+
+// PNG returns the QR code encoded as a PNG image.
+func (c *Code) PNG(opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, c.Image(opts...))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG returns the QR code as an SVG document that scales to any size, one unit is one module.
+func (c *Code) SVG(opts ...Option) string {
+	o := newOptions(opts)
+	size := c.size + 2*o.border
+
+	var path strings.Builder
+	for y := range c.size {
+		for x := range c.size {
+			if c.Dark(x, y) {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+o.border, y+o.border)
+			}
+		}
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#ffffff"/><path d="%s" fill="#000000"/></svg>`, size, size, path.String())
+}
+
+// Terminal returns the QR code drawn with Unicode half blocks, each line of text holds two rows of modules.
+// The light modules are drawn, which shows the code correctly on terminals with a dark background.
+func (c *Code) Terminal(opts ...Option) string {
+	o := newOptions(opts)
+
+	drawn := func(x, y int) bool {
+		return c.Dark(x, y) == o.inverted
+	}
+
+	var b strings.Builder
+	for y := -o.border; y < c.size+o.border; y += 2 {
+		for x := -o.border; x < c.size+o.border; x++ {
+			top, bottom := drawn(x, y), drawn(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// PNG encodes the payload and returns it as a PNG image.
+func PNG(payload string, opts ...Option) ([]byte, error) {
+	c, err := Encode(payload, newOptions(opts).level)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.PNG(opts...)
+}
+
+// SVG encodes the payload and returns it as an SVG document.
+func SVG(payload string, opts ...Option) (string, error) {
+	c, err := Encode(payload, newOptions(opts).level)
+	if err != nil {
+		return "", err
+	}
+
+	return c.SVG(opts...), nil
+}
+
+// Terminal encodes the payload and returns it drawn with Unicode half blocks.
+func Terminal(payload string, opts ...Option) (string, error) {
+	c, err := Encode(payload, newOptions(opts).level)
+	if err != nil {
+		return "", err
+	}
+
+	return c.Terminal(opts...), nil
+}
+
+// Handler serves the current QR code of the session as a PNG image, the client should request it again every second.
+// After the session expired the handler responds with 410 Gone.
+func Handler(session *bankid.QRSession, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := session.Current()
+		if errors.Is(err, bankid.ErrQRCodeExpired) {
+			http.Error(w, err.Error(), http.StatusGone)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		img, err := PNG(payload, opts...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(img)
+	})
+}
@@ -0,0 +1,444 @@
+package bankid
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// set when redaction is turned off, the zero value keeps redaction on
+var unredacted atomic.Bool
+
+// SetRedaction turns the redaction of personal data on or off. Redaction is on by default.
+// It masks personal numbers, names and IP addresses in errors, warnings, log values and the String methods of users,
+// completion data and requests. Only turn it off where the output may hold personal data, e.g. when debugging locally.
+func SetRedaction(enabled bool) {
+	unredacted.Store(!enabled)
+}
+
+// RedactionEnabled reports whether personal data is redacted, see SetRedaction.
+func RedactionEnabled() bool {
+	return !unredacted.Load()
+}
+
+var personalNumberPattern = regexp.MustCompile(`\b(\d{6}|\d{8})[-+]?\d{4}\b`)
+
+// redactText masks the IP addresses and personal numbers in a text, e.g. the message of an error.
+func redactText(s string) string {
+	if !RedactionEnabled() {
+		return s
+	}
+
+	s = redactIPs(s)
+	return personalNumberPattern.ReplaceAllStringFunc(s, redactPersonalNumber)
+}
+
+// redactIPs masks the IPv4 and IPv6 addresses in a text. Every run of the characters of an address is parsed with net.ParseIP,
+// with the port of "host:port" and the punctuation ending a sentence removed.
+func redactIPs(s string) string {
+	isIPChar := func(ch byte) bool {
+		return ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F' || ch == '.' || ch == ':'
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if !isIPChar(s[i]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(s) && isIPChar(s[j]) {
+			j++
+		}
+
+		token := s[i:j]
+		host := strings.TrimRight(token, ".:")
+		if net.ParseIP(host) == nil && strings.Count(host, ":") == 1 {
+			host, _, _ = strings.Cut(host, ":")
+		}
+
+		// letters of a word are not the start of an address
+		if net.ParseIP(host) != nil && (i == 0 || !isWordChar(s[i-1])) && (j == len(s) || !isWordChar(s[j])) {
+			b.WriteString(redactIP(host))
+			b.WriteString(token[len(host):])
+		} else {
+			b.WriteString(token)
+		}
+
+		i = j
+	}
+
+	return b.String()
+}
+
+func isWordChar(ch byte) bool {
+	return ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+}
+
+// redactPersonalNumber masks the digits of a personal number except the year of birth, e.g. 1995********.
+func redactPersonalNumber(s string) string {
+	if !RedactionEnabled() || s == "" {
+		return s
+	}
+
+	digits := 0
+	for _, ch := range s {
+		if ch >= '0' && ch <= '9' {
+			digits++
+		}
+	}
+
+	// the year of birth is kept for the 10 and 12 digit formats
+	keep := 0
+	if digits == 10 || digits == 12 {
+		keep = digits - 8
+	}
+
+	var b strings.Builder
+	for _, ch := range s {
+		switch {
+		case ch < '0' || ch > '9':
+			b.WriteRune(ch)
+		case keep > 0:
+			b.WriteRune(ch)
+			keep--
+		default:
+			b.WriteRune('*')
+		}
+	}
+
+	return b.String()
+}
+
+// redactName masks every part of a name except its first letter, e.g. K*** K***.
+func redactName(s string) string {
+	if !RedactionEnabled() || s == "" {
+		return s
+	}
+
+	parts := strings.Fields(s)
+	for i, part := range parts {
+		r, _ := utf8.DecodeRuneInString(part)
+		parts[i] = string(r) + "***"
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// redactIP masks the host part of an IP address: the last two bytes of IPv4 and all but the first 32 bits of IPv6.
+func redactIP(s string) string {
+	if !RedactionEnabled() || s == "" {
+		return s
+	}
+
+	ip := net.ParseIP(s)
+	switch {
+	case ip == nil:
+		return "***"
+	case ip.To4() != nil:
+		ip = ip.To4()
+		return fmt.Sprintf("%d.%d.*.*", ip[0], ip[1])
+	default:
+		return ip.Mask(net.CIDRMask(32, 128)).String() + "*"
+	}
+}
+
+// redactURL keeps the scheme, host and path of a URL and masks its user info, query and fragment,
+// which may hold personal data of the user the URL returns to.
+func redactURL(s string) string {
+	if !RedactionEnabled() || s == "" {
+		return s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "***"
+	}
+
+	if u.User != nil {
+		u.User = url.User("***")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "***"
+	}
+	if u.Fragment != "" {
+		u.Fragment, u.RawFragment = "***", ""
+	}
+
+	return u.String()
+}
+
+// redactData replaces data that may hold personal data with its length.
+func redactData(s string) string {
+	if !RedactionEnabled() || s == "" {
+		return s
+	}
+
+	return fmt.Sprintf("[%d bytes]", len(s))
+}
+
+func (u User) redacted() User {
+	return User{
+		PersonalNumber: redactPersonalNumber(u.PersonalNumber),
+		Name:           redactName(u.Name),
+		GivenName:      redactName(u.GivenName),
+		Surname:        redactName(u.Surname),
+	}
+}
+
+// LogValue logs the user with the personal number and names redacted.
+func (u User) LogValue() slog.Value {
+	u = u.redacted()
+
+	return slog.GroupValue(
+		slog.String("personalNumber", u.PersonalNumber),
+		slog.String("name", u.Name),
+		slog.String("givenName", u.GivenName),
+		slog.String("surname", u.Surname),
+	)
+}
+
+// String formats the user with the personal number and names redacted.
+func (u User) String() string {
+	type plain User
+	return fmt.Sprintf("%+v", plain(u.redacted()))
+}
+
+func (d Device) redacted() Device {
+	return Device{
+		IpAddress: redactIP(d.IpAddress),
+		Uhi:       redactData(d.Uhi),
+	}
+}
+
+// LogValue logs the device with the IP address and the hardware identifier redacted.
+func (d Device) LogValue() slog.Value {
+	d = d.redacted()
+
+	return slog.GroupValue(
+		slog.String("ipAddress", d.IpAddress),
+		slog.String("uhi", d.Uhi),
+	)
+}
+
+// String formats the device with the IP address and the hardware identifier redacted.
+func (d Device) String() string {
+	type plain Device
+	return fmt.Sprintf("%+v", plain(d.redacted()))
+}
+
+// the signature and the OCSP response hold the user's certificate, they are replaced with their length.
+// The user and the device redact themselves.
+func (c CompletionData) redacted() CompletionData {
+	c.Signature = redactData(c.Signature)
+	c.OcspResponse = redactData(c.OcspResponse)
+
+	return c
+}
+
+// LogValue logs the completion data with the user, the device, the signature and the OCSP response redacted.
+func (c CompletionData) LogValue() slog.Value {
+	r := c.redacted()
+
+	return slog.GroupValue(
+		slog.Any("user", c.User),
+		slog.Any("device", c.Device),
+		slog.String("bankIdIssueDate", r.BankIdIssueDate),
+		slog.Bool("stepUp", r.StepUp),
+		slog.String("signature", r.Signature),
+		slog.String("ocspResponse", r.OcspResponse),
+		slog.String("risk", string(r.Risk)),
+	)
+}
+
+// String formats the completion data with the user, the device, the signature and the OCSP response redacted.
+func (c CompletionData) String() string {
+	type plain CompletionData
+	return fmt.Sprintf("%+v", plain(c.redacted()))
+}
+
+// LogValue logs the collect response with the completion data redacted.
+func (r CollectResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("orderRef", r.OrderRef),
+		slog.String("status", string(r.Status)),
+		slog.String("hintCode", string(r.HintCode)),
+		slog.Any("completionData", r.CompletionData),
+	)
+}
+
+func (u VerifiedUser) redacted() VerifiedUser {
+	return VerifiedUser{
+		PersonalNumber: redactPersonalNumber(u.PersonalNumber),
+		Name:           redactName(u.Name),
+		GivenName:      redactName(u.GivenName),
+		Surname:        redactName(u.Surname),
+		Age:            u.Age,
+	}
+}
+
+// LogValue logs the holder of the ID card with the personal number and names redacted.
+func (u VerifiedUser) LogValue() slog.Value {
+	u = u.redacted()
+
+	return slog.GroupValue(
+		slog.String("personalNumber", u.PersonalNumber),
+		slog.String("name", u.Name),
+		slog.String("givenName", u.GivenName),
+		slog.String("surname", u.Surname),
+		slog.Int("age", u.Age),
+	)
+}
+
+// String formats the holder of the ID card with the personal number and names redacted.
+func (u VerifiedUser) String() string {
+	type plain VerifiedUser
+	return fmt.Sprintf("%+v", plain(u.redacted()))
+}
+
+// LogValue logs the verify response with the holder of the ID card and the signature redacted.
+func (r VerifyResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("user", r.User),
+		slog.String("verificationId", r.Verification.VerificationID),
+		slog.Time("verifiedAt", r.Verification.VerifiedAt),
+		slog.String("signature", redactData(r.Verification.Signature)),
+		slog.Time("identifiedAt", r.Authentication.IdentifiedAt),
+	)
+}
+
+// String formats the verify response with the holder of the ID card and the signature redacted.
+func (r VerifyResponse) String() string {
+	type plain VerifyResponse
+	r.Verification.Signature = redactData(r.Verification.Signature)
+	return fmt.Sprintf("%+v", plain(r))
+}
+
+// LogValue logs the personnummer with all but the year of birth redacted.
+func (p Personnummer) LogValue() slog.Value {
+	return slog.StringValue(redactPersonalNumber(p.String()))
+}
+
+// redactRequest returns a copy of a request with the personal data redacted, the request of the caller is left unchanged.
+func redactRequest(rb RequestBody) RequestBody {
+	requirement := func(r *Requirement) *Requirement {
+		if r == nil {
+			return nil
+		}
+
+		c := *r
+		c.PersonalNumber = redactPersonalNumber(r.PersonalNumber)
+		return &c
+	}
+
+	app := func(a *App) *App {
+		if a == nil {
+			return nil
+		}
+
+		c := *a
+		c.DeviceIdentifier = redactData(a.DeviceIdentifier)
+		return &c
+	}
+
+	web := func(w *Web) *Web {
+		if w == nil {
+			return nil
+		}
+
+		c := *w
+		c.DeviceIdentifier = redactData(w.DeviceIdentifier)
+		return &c
+	}
+
+	switch v := rb.(type) {
+	case AuthRequest:
+		v.EndUserIP = redactIP(v.EndUserIP)
+		v.Requirement = requirement(v.Requirement)
+		v.UserVisibleData, v.UserNonVisibleData = redactData(v.UserVisibleData), redactData(v.UserNonVisibleData)
+		v.App, v.Web = app(v.App), web(v.Web)
+		return v
+
+	case SignRequest:
+		v.EndUserIP = redactIP(v.EndUserIP)
+		v.Requirement = requirement(v.Requirement)
+		v.UserVisibleData, v.UserNonVisibleData = redactData(v.UserVisibleData), redactData(v.UserNonVisibleData)
+		v.App, v.Web = app(v.App), web(v.Web)
+		return v
+
+	case PhoneAuthRequest:
+		v.PersonalNumber = redactPersonalNumber(v.PersonalNumber)
+		v.Requirement = requirement(v.Requirement)
+		v.UserVisibleData, v.UserNonVisibleData = redactData(v.UserVisibleData), redactData(v.UserNonVisibleData)
+		return v
+
+	case PhoneSignRequest:
+		v.PersonalNumber = redactPersonalNumber(v.PersonalNumber)
+		v.Requirement = requirement(v.Requirement)
+		v.UserVisibleData, v.UserNonVisibleData = redactData(v.UserVisibleData), redactData(v.UserNonVisibleData)
+		return v
+
+	case PaymentRequest:
+		v.EndUserIP = redactIP(v.EndUserIP)
+		v.Requirement = requirement(v.Requirement)
+		v.UserVisibleData, v.UserNonVisibleData = redactData(v.UserVisibleData), redactData(v.UserNonVisibleData)
+		v.App, v.Web = app(v.App), web(v.Web)
+		return v
+	}
+
+	return rb
+}
+
+// requestLogValue logs the JSON fields of a request with the personal data redacted.
+func requestLogValue(rb RequestBody) slog.Value {
+	b, err := redactRequest(rb).Marshal()
+	if err != nil {
+		return slog.StringValue(fmt.Sprintf("%T", rb))
+	}
+
+	var fields map[string]any
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return slog.StringValue(fmt.Sprintf("%T", rb))
+	}
+
+	return slog.AnyValue(fields)
+}
+
+// LogValue logs the request with the IP address, personal number and user data redacted.
+func (r AuthRequest) LogValue() slog.Value { return requestLogValue(r) }
+
+// LogValue logs the request with the IP address, personal number and user data redacted.
+func (r SignRequest) LogValue() slog.Value { return requestLogValue(r) }
+
+// LogValue logs the request with the personal numbers and user data redacted.
+func (r PhoneAuthRequest) LogValue() slog.Value { return requestLogValue(r) }
+
+// LogValue logs the request with the personal numbers and user data redacted.
+func (r PhoneSignRequest) LogValue() slog.Value { return requestLogValue(r) }
+
+// LogValue logs the request with the IP address, personal number and user data redacted.
+func (r PaymentRequest) LogValue() slog.Value { return requestLogValue(r) }
+
+// String formats the request with the IP address, personal number and user data redacted.
+func (r AuthRequest) String() string { return requestLogValue(r).String() }
+
+// String formats the request with the IP address, personal number and user data redacted.
+func (r SignRequest) String() string { return requestLogValue(r).String() }
+
+// String formats the request with the personal numbers and user data redacted.
+func (r PhoneAuthRequest) String() string { return requestLogValue(r).String() }
+
+// String formats the request with the personal numbers and user data redacted.
+func (r PhoneSignRequest) String() string { return requestLogValue(r).String() }
+
+// String formats the request with the IP address, personal number and user data redacted.
+func (r PaymentRequest) String() string { return requestLogValue(r).String() }
@@ -0,0 +1,115 @@
+package bankid
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	c := CompletionData{
+		User:      User{PersonalNumber: "199510221287", Name: "Karl Karlsson", GivenName: "Karl", Surname: "Karlsson"},
+		Device:    Device{IpAddress: "192.168.10.21", Uhi: "OZvYM9VvyiAmG7NA5jU5zRGcPn0="},
+		Signature: "PD94bWwgdmVyc2lvbj0iMS4wIj8+",
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	logger.Info("completed", "completionData", c, "request", PhoneAuthRequest{PersonalNumber: "19951022-1287", CallInitiator: "user"})
+	logger.Info("started", "request", AuthRequest{EndUserIP: "2001:db8:85a3::8a2e:370:7334", Requirement: &Requirement{PersonalNumber: "199510221287"}})
+
+	dumps := []string{
+		logs.String(),
+		fmt.Sprintf("%v", CollectResponse{Status: Complete, CompletionData: c}),
+		fmt.Sprintf("%+v", SignRequest{EndUserIP: "192.168.10.21", UserVisibleData: "Karl Karlsson godkänner"}),
+	}
+
+	for _, dump := range dumps {
+		for _, personal := range []string{"199510221287", "1287", "Karlsson", "10.21", "OZvYM9", "PD94bWwg", "8a2e", "godkänner"} {
+			if strings.Contains(dump, personal) {
+				t.Errorf("expected %q to be redacted in %s", personal, dump)
+			}
+		}
+	}
+
+	if !strings.Contains(logs.String(), `"personalNumber":"1995********"`) || !strings.Contains(logs.String(), `"name":"K*** K***"`) {
+		t.Errorf("expected the redacted personal number and name, got %s", logs.String())
+	}
+
+	// errors and warnings of invalid input
+	b := &bankid{config: &RequestConfig{}}
+	_, err := b.Auth(context.Background(), AuthRequest{EndUserIP: "192.168.10.256"})
+	if err == nil || strings.Contains(err.Error(), "10.256") {
+		t.Errorf("expected the invalid IP to be redacted, got %v", err)
+	}
+
+	_, err = b.Auth(context.Background(), AuthRequest{EndUserIP: "2001:db8:85a3::8a2e::7334"})
+	if err == nil || strings.Contains(err.Error(), "7334") {
+		t.Errorf("expected the invalid IPv6 address to be redacted, got %v", err)
+	}
+
+	_, err = b.Auth(context.Background(), AuthRequest{EndUserIP: "2001:db8:85a3::8a2e:370:7334", ReturnURL: "http://example.com/done?name=Karl+Karlsson#pnr"})
+	if err == nil || strings.Contains(err.Error(), "Karl") || strings.Contains(err.Error(), "pnr") || !strings.Contains(err.Error(), "http://example.com/done?***#***") {
+		t.Errorf("expected the query of the return URL to be redacted, got %v", err)
+	}
+
+	err = InputInvalidError{Message: "ReturnURL: https://example.com/?pnr=19951022-1287 is invalid"}
+	if !strings.Contains(err.Error(), "1995****-****") {
+		t.Errorf("expected the personal number to be redacted, got %v", err)
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	tests := map[string]string{
+		"request from 2001:db8:85a3::8a2e:370:7334 failed":    "request from 2001:db8::* failed",
+		"request from [2001:db8:85a3::8a2e:370:7334]:443":     "request from [2001:db8::*]:443",
+		"request from ::ffff:192.168.10.21.":                  "request from 192.168.*.*.",
+		"request from 192.168.10.21:8080, user 19951022-1287": "request from 192.168.*.*:8080, user 1995****-****",
+		"policy 1.2.752.78.1.5 at 12:30 of cafe:babe":         "policy 1.2.752.78.1.5 at 12:30 of cafe:babe",
+	}
+
+	for input, want := range tests {
+		if got := redactText(input); got != want {
+			t.Errorf("redactText(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRedactResponses(t *testing.T) {
+	user := User{PersonalNumber: "199510221287", Name: "Karl Karlsson", GivenName: "Karl", Surname: "Karlsson"}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	logger.Info("collected", "response", CollectResponse{Status: Complete, CompletionData: CompletionData{User: user, Device: Device{IpAddress: "2001:db8:85a3::8a2e:370:7334"}}})
+	logger.Info("verified", "response", VerifyResponse{User: VerifiedUser{PersonalNumber: user.PersonalNumber, Name: user.Name, GivenName: user.GivenName, Surname: user.Surname, Age: 30}})
+
+	dumps := []string{
+		logs.String(),
+		fmt.Sprintf("%+v", VerifyResponse{User: VerifiedUser{PersonalNumber: user.PersonalNumber, Name: user.Name}}),
+	}
+
+	for _, dump := range dumps {
+		for _, personal := range []string{"1287", "Karl", "8a2e"} {
+			if strings.Contains(dump, personal) {
+				t.Errorf("expected %q to be redacted in %s", personal, dump)
+			}
+		}
+	}
+}
+
+func TestRedactionOptOut(t *testing.T) {
+	SetRedaction(false)
+	t.Cleanup(func() { SetRedaction(true) })
+
+	user := User{PersonalNumber: "199510221287", Name: "Karl Karlsson"}
+	if s := user.String(); !strings.Contains(s, "199510221287") || !strings.Contains(s, "Karl Karlsson") {
+		t.Errorf("expected the user without redaction, got %s", s)
+	}
+
+	if s := (AuthRequest{EndUserIP: "192.168.10.21"}).String(); !strings.Contains(s, "192.168.10.21") {
+		t.Errorf("expected the request without redaction, got %s", s)
+	}
+}
@@ -2,6 +2,8 @@ package bankid
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/url"
 )
 
 // RequestBody is an interface for all BankID requests.
@@ -33,6 +35,20 @@ type AuthRequest struct {
 	// will potentially make the text displayed to the user nicer to look at.
 	// For instructions check out https://www.bankid.com/utvecklare/guider/formatera-text
 	UserVisibleDataFormat string `json:"userVisibleDataFormat,omitempty"`
+
+	// Optional: The URL the user is returned to when the BankID app was started with the autoStartToken on the same device.
+	// Must be an absolute https URL. Make sure the URL leads back to the same browser or app session that started the order.
+	ReturnURL string `json:"returnUrl,omitempty"`
+
+	// Optional: If set to true, a risk indication is returned in the completionData of the order, see CompletionData.Risk.
+	ReturnRisk bool `json:"returnRisk,omitempty"`
+
+	// Optional: Information about the app the user started the order from, improves BankID's risk assessment. Can't be combined with Web.
+	App *App `json:"app,omitempty"`
+
+	// Optional: Information about the browser the user started the order from, improves BankID's risk assessment. Can't be combined with App.
+	// Use WebFromRequest to fill it from the incoming request.
+	Web *Web `json:"web,omitempty"`
 }
 
 func (r AuthRequest) Marshal() ([]byte, error) {
@@ -59,6 +75,20 @@ type SignRequest struct {
 	// will potentially make the text displayed to the user nicer to look at.
 	// For instructions check out https://www.bankid.com/utvecklare/guider/formatera-text
 	UserVisibleDataFormat string `json:"userVisibleDataFormat,omitempty"`
+
+	// Optional: The URL the user is returned to when the BankID app was started with the autoStartToken on the same device.
+	// Must be an absolute https URL. Make sure the URL leads back to the same browser or app session that started the order.
+	ReturnURL string `json:"returnUrl,omitempty"`
+
+	// Optional: If set to true, a risk indication is returned in the completionData of the order, see CompletionData.Risk.
+	ReturnRisk bool `json:"returnRisk,omitempty"`
+
+	// Optional: Information about the app the user started the order from, improves BankID's risk assessment. Can't be combined with Web.
+	App *App `json:"app,omitempty"`
+
+	// Optional: Information about the browser the user started the order from, improves BankID's risk assessment. Can't be combined with App.
+	// Use WebFromRequest to fill it from the incoming request.
+	Web *Web `json:"web,omitempty"`
 }
 
 func (r SignRequest) Marshal() ([]byte, error) {
@@ -91,6 +121,10 @@ type PhoneAuthRequest struct {
 	// will potentially make the text displayed to the user nicer to look at.
 	// For instructions check out https://www.bankid.com/utvecklare/guider/formatera-text
 	UserVisibleDataFormat string `json:"userVisibleDataFormat,omitempty"`
+
+	// Optional: If set to true, a risk indication is returned in the completionData of the order, see CompletionData.Risk.
+	// Phone orders don't start the BankID app on the same device, so they have no returnUrl.
+	ReturnRisk bool `json:"returnRisk,omitempty"`
 }
 
 func (r PhoneAuthRequest) Marshal() ([]byte, error) {
@@ -120,12 +154,122 @@ type PhoneSignRequest struct {
 	// will potentially make the text displayed to the user nicer to look at.
 	// For instructions check out https://www.bankid.com/utvecklare/guider/formatera-text
 	UserVisibleDataFormat string `json:"userVisibleDataFormat,omitempty"`
+
+	// Optional: If set to true, a risk indication is returned in the completionData of the order, see CompletionData.Risk.
+	// Phone orders don't start the BankID app on the same device, so they have no returnUrl.
+	ReturnRisk bool `json:"returnRisk,omitempty"`
 }
 
 func (r PhoneSignRequest) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
+// Initiates a payment order where the user approves a transaction that the BankID app renders natively.
+// Use the collect method to query the status of the order.
+type PaymentRequest struct {
+	// Required: The user IP address as seen by RP. String. IPv4 and IPv6 is allowed.
+	// Correct IP address must be the IP address representing the user agent (the end user device) as seen by the RP.
+	EndUserIP string `json:"endUserIp"`
+
+	// Required: The transaction the user approves, displayed by the BankID app.
+	UserVisibleTransaction UserVisibleTransaction `json:"userVisibleTransaction"`
+
+	// Optional: Requirements on how the payment order must be performed.
+	Requirement *Requirement `json:"requirement,omitempty"`
+
+	// Optional: Text displayed to the user below the transaction. String. The text can be formatted using CR, LF and CRLF for new lines.
+	// The text must be encoded as UTF-8 and then base 64 encoded. 1—1 500 characters after base 64 encoding.
+	UserVisibleData string `json:"userVisibleData,omitempty"`
+
+	// Optional: Data is not displayed to the user. String. The value must be base 64-encoded. 1-1 500 characters after base 64-encoding.
+	UserNonVisibleData string `json:"userNonVisibleData,omitempty"`
+
+	// Optional: If present, and set to “simpleMarkdownV1”, this parameter indicates that userVisibleData holds formatting characters which,
+	// will potentially make the text displayed to the user nicer to look at.
+	// For instructions check out https://www.bankid.com/utvecklare/guider/formatera-text
+	UserVisibleDataFormat string `json:"userVisibleDataFormat,omitempty"`
+
+	// Optional: The URL the user is returned to when the BankID app was started with the autoStartToken on the same device.
+	// Must be an absolute https URL. Make sure the URL leads back to the same browser or app session that started the order.
+	ReturnURL string `json:"returnUrl,omitempty"`
+
+	// Optional: If set to true, a risk indication is returned in the completionData of the order, see CompletionData.Risk.
+	ReturnRisk bool `json:"returnRisk,omitempty"`
+
+	// Optional: Information about the app the user started the order from, improves BankID's risk assessment. Can't be combined with Web.
+	App *App `json:"app,omitempty"`
+
+	// Optional: Information about the browser the user started the order from, improves BankID's risk assessment. Can't be combined with App.
+	// Use WebFromRequest to fill it from the incoming request.
+	Web *Web `json:"web,omitempty"`
+
+	// Optional: Flags that the RP has detected for the transaction, they are taken into account in BankID's risk assessment.
+	RiskFlags []RiskFlag `json:"riskFlags,omitempty"`
+}
+
+func (r PaymentRequest) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// The transaction of a payment order, the BankID app displays it to the user.
+type UserVisibleTransaction struct {
+	// Required: The type of the transaction.
+	TransactionType TransactionType `json:"transactionType"`
+
+	// Required: The recipient of the payment.
+	Recipient Recipient `json:"recipient"`
+
+	// Optional: The amount of the payment, required for card transactions.
+	Money *Money `json:"money,omitempty"`
+
+	// Optional: A warning displayed to the user before they approve the transaction, e.g. when the recipient is unknown.
+	RiskWarning string `json:"riskWarning,omitempty"`
+}
+
+// TransactionType of a payment order.
+type TransactionType string
+
+const (
+	// A card payment.
+	TransactionTypeCard TransactionType = "card"
+
+	// A non-payment authentication, e.g. when a card is added to a wallet, it has no amount.
+	TransactionTypeNPA TransactionType = "npa"
+)
+
+// The recipient of a payment.
+type Recipient struct {
+	// Required: The name of the recipient, e.g. the merchant.
+	Name string `json:"name"`
+}
+
+// Money is an amount in a currency.
+type Money struct {
+	// Required: The amount with a comma as decimal separator and up to two decimals, e.g. "1249,50".
+	Amount string `json:"amount"`
+
+	// Required: The ISO 4217 code of the currency, e.g. "SEK".
+	Currency string `json:"currency"`
+}
+
+// RiskFlag describes a risk that the RP detected for a payment.
+type RiskFlag string
+
+const (
+	RiskFlagNewCard                  RiskFlag = "newCard"
+	RiskFlagNewCustomer              RiskFlag = "newCustomer"
+	RiskFlagNewRecipient             RiskFlag = "newRecipient"
+	RiskFlagHighRiskRecipient        RiskFlag = "highRiskRecipient"
+	RiskFlagLargeAmount              RiskFlag = "largeAmount"
+	RiskFlagForeignCurrency          RiskFlag = "foreignCurrency"
+	RiskFlagCryptocurrencyPurchase   RiskFlag = "cryptocurrencyPurchase"
+	RiskFlagMoneyTransfer            RiskFlag = "moneyTransfer"
+	RiskFlagOverseasTransaction      RiskFlag = "overseasTransaction"
+	RiskFlagRecurringPayment         RiskFlag = "recurringPayment"
+	RiskFlagSuspiciousPaymentPattern RiskFlag = "suspiciousPaymentPattern"
+	RiskFlagOther                    RiskFlag = "other"
+)
+
 // Collects the result of a sign or auth order using orderRef as reference. RP should keep on calling collect every two seconds if status is pending. RP must abort if status indicates failed. The user identity is returned when complete.
 type CollectRequest struct {
 	// The orderRef returned from auth or sign.
@@ -146,13 +290,23 @@ func (r CancelRequest) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
+// Verifies the QR code of a person's BankID digital ID card, e.g. at a counter where the person is physically present.
+type VerifyRequest struct {
+	// Required: The content of the QR code shown in the digital ID card of the BankID app.
+	QRCode string `json:"qrCode"`
+}
+
+func (r VerifyRequest) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
 // RP may use the requirement parameter to describe how a signature must be created and verified.
 // A typical use case is to require Mobile BankID or a certain card reader.
 // The following table describes requirements, their possible values and defaults.
 type Requirement struct {
 	// Users are required to sign the transaction with their PIN code, even if they have biometrics activated.
 	// 	- Default: False, the user is not required to use pin code.
-	Pincode bool `json:"pincode,omitempty"`
+	Pincode bool `json:"pinCode,omitempty"`
 
 	// If present, and set to "true", the client needs to provide MRTD (Machine readable travel document) information to complete the order.
 	// Only Swedish passports and national ID cards are supported.
@@ -195,3 +349,50 @@ type Requirement struct {
 	// If a BankID with another personal number attempts to sign the transaction, it fails.
 	PersonalNumber string `json:"personalNumber,omitempty"`
 }
+
+// Device information for orders started from the RP's own app.
+type App struct {
+	// Required: The identifier of the RP's app, e.g. the bundle identifier "com.example.app".
+	AppIdentifier string `json:"appIdentifier"`
+
+	// Required: The operating system and version of the device, e.g. "IOS 17.4".
+	DeviceOS string `json:"deviceOS"`
+
+	// Required: The model of the device, e.g. "Apple iPhone14,3".
+	DeviceModelName string `json:"deviceModelName"`
+
+	// Required: An identifier of the device that is stable between orders, e.g. a hash of an installation id.
+	DeviceIdentifier string `json:"deviceIdentifier"`
+}
+
+// Device information for orders started from a web browser.
+type Web struct {
+	// Required: The domain of the web page that started the order, e.g. "example.com".
+	ReferringDomain string `json:"referringDomain"`
+
+	// Required: The User-Agent header of the user's browser.
+	UserAgent string `json:"userAgent"`
+
+	// Required: An identifier of the browser that is stable between orders, e.g. a hash of a cookie with at least 128 bits of randomness.
+	DeviceIdentifier string `json:"deviceIdentifier"`
+}
+
+// WebFromRequest fills the web device information from the request that starts the order.
+// The referring domain is taken from the Origin header, the Referer header or the Host of the request, in that order.
+func WebFromRequest(r *http.Request, deviceIdentifier string) *Web {
+	// the referring domain has no port, and an IPv6 address has no brackets, e.g. "[::1]:8443" becomes "::1"
+	domain := (&url.URL{Host: r.Host}).Hostname()
+	for _, header := range []string{"Origin", "Referer"} {
+		u, err := url.Parse(r.Header.Get(header))
+		if err == nil && u.Host != "" {
+			domain = u.Hostname()
+			break
+		}
+	}
+
+	return &Web{
+		ReferringDomain:  domain,
+		UserAgent:        r.UserAgent(),
+		DeviceIdentifier: deviceIdentifier,
+	}
+}
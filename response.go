@@ -1,6 +1,9 @@
 package bankid
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ResponseBody is an interface for all successfull BankID responses.
 type ResponseBody interface {
@@ -8,9 +11,10 @@ type ResponseBody interface {
 	Unmarshal(data []byte) error
 }
 
+// Response body received from BankID when the request failed.
 type ErrorResponseBody struct {
-	ErrorCode int    `json:"errorCode"`
-	Details   string `json:"details"`
+	ErrorCode ErrorCode `json:"errorCode"`
+	Details   string    `json:"details"`
 }
 
 // Response received from the auth endpoint, example of the response body:
@@ -25,7 +29,7 @@ type AuthResponse struct {
 	// Used to collect the status of the order.
 	OrderRef string `json:"orderRef"`
 
-	// Used to compile the start url according to launching.
+	// Used to compile the start url according to launching, see AutoStartLink.
 	// See https://www.bankid.com/utvecklare/guider/teknisk-integrationsguide/programstart
 	AutoStartToken string `json:"autoStartToken"`
 
@@ -44,7 +48,7 @@ type SignResponse struct {
 	// Used to collect the status of the order.
 	OrderRef string `json:"orderRef"`
 
-	// Used to compile the start url according to launching.
+	// Used to compile the start url according to launching, see AutoStartLink.
 	// See https://www.bankid.com/utvecklare/guider/teknisk-integrationsguide/programstart
 	AutoStartToken string `json:"autoStartToken"`
 
@@ -59,6 +63,25 @@ func (r SignResponse) Unmarshal(data []byte) error {
 	return json.Unmarshal(data, &r)
 }
 
+type PaymentResponse struct {
+	// Used to collect the status of the order.
+	OrderRef string `json:"orderRef"`
+
+	// Used to compile the start url according to launching, see AutoStartLink.
+	// See https://www.bankid.com/utvecklare/guider/teknisk-integrationsguide/programstart
+	AutoStartToken string `json:"autoStartToken"`
+
+	// Used to compute the animated QR code.
+	QrStartToken string `json:"qrStartToken"`
+
+	// Used to compute the animated QR code.
+	QrStartSecret string `json:"qrStartSecret"`
+}
+
+func (r PaymentResponse) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, &r)
+}
+
 type PhoneAuthResponse struct {
 	// Used to collect the status of the order.
 	OrderRef string `json:"orderRef"`
@@ -114,6 +137,42 @@ type CompletionData struct {
 	StepUp          bool   `json:"stepUp,omitempty"`
 	Signature       string `json:"signature,omitempty"`
 	OcspResponse    string `json:"ocspResponse,omitempty"`
+
+	// The risk indication of the order, only returned when the order was started with returnRisk set to true.
+	Risk RiskLevel `json:"risk,omitempty"`
+}
+
+// RiskLevel is BankID's indication of the risk that the order is part of a fraud attempt.
+type RiskLevel string
+
+const (
+	// No or low risk identified in the available order data.
+	RiskLow RiskLevel = "low"
+
+	// Might need further action from the RP depending on the type of service.
+	RiskModerate RiskLevel = "moderate"
+
+	// The order should be blocked or cancelled by the RP.
+	RiskHigh RiskLevel = "high"
+)
+
+// rank orders the risk levels, unknown levels rank above high so they are never accepted by mistake.
+func (r RiskLevel) rank() int {
+	switch r {
+	case RiskLow:
+		return 1
+	case RiskModerate:
+		return 2
+	case RiskHigh:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Exceeds reports whether the risk level is higher than max.
+func (r RiskLevel) Exceeds(max RiskLevel) bool {
+	return r.rank() > max.rank()
 }
 
 type Status string
@@ -161,6 +220,9 @@ const (
 	// The order was cancelled. The system received a new order for the user.
 	Cancelled HintCode = "cancelled"
 
+	// The user declined the call from the RP, only returned for PhoneAuth and PhoneSign orders started by the RP.
+	UserDeclinedCall HintCode = "userDeclinedCall"
+
 	// The user did not provide their ID or the client did not launch within a certain time limit. Potential
 	// causes are:
 	// 	1. RP did not use autoStartToken when launching the BankID security app. RP must correct this in their implementation.
@@ -174,3 +236,71 @@ type CancelResponse struct{}
 func (r CancelResponse) Unmarshal(data []byte) error {
 	return nil
 }
+
+// Response received from the verify endpoint when the digital ID card is authentic, example of the response body:
+//
+//	{
+//	    "user": {
+//	        "personalNumber": "190000000000",
+//	        "name": "Karl Karlsson",
+//	        "givenName": "Karl",
+//	        "surname": "Karlsson",
+//	        "age": 36
+//	    },
+//	    "verification": {
+//	        "verificationId": "d4b3a8c1-3fb2-4d4f-a5a3-92c7b1a1c5e2",
+//	        "verifiedAt": "2024-05-16T09:21:04Z",
+//	        "signature": "<base64-encoded data>"
+//	    },
+//	    "authentication": {
+//	        "identifiedAt": "2024-05-16T09:20:51Z"
+//	    }
+//	}
+type VerifyResponse struct {
+	// The identity of the holder of the digital ID card.
+	User VerifiedUser `json:"user"`
+
+	// Information about the verification that BankID performed.
+	Verification Verification `json:"verification"`
+
+	// Information about when the holder identified themselves to show the ID card.
+	Authentication VerifyAuthentication `json:"authentication"`
+}
+
+func (r VerifyResponse) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, &r)
+}
+
+// The identity of the holder of a digital ID card.
+type VerifiedUser struct {
+	// The personal number.
+	PersonalNumber string `json:"personalNumber"`
+
+	// The given name and surname.
+	Name string `json:"name"`
+
+	// The given name.
+	GivenName string `json:"givenName"`
+
+	// The surname.
+	Surname string `json:"surname"`
+
+	// The age of the holder in years.
+	Age int `json:"age"`
+}
+
+type Verification struct {
+	// Unique identifier of the verification, keep it as a reference to the check.
+	VerificationID string `json:"verificationId"`
+
+	// The time BankID verified the ID card.
+	VerifiedAt time.Time `json:"verifiedAt"`
+
+	// Signature of the verification by BankID, base64 encoded.
+	Signature string `json:"signature"`
+}
+
+type VerifyAuthentication struct {
+	// The time the holder identified themselves with BankID to open the ID card.
+	IdentifiedAt time.Time `json:"identifiedAt"`
+}
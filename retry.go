@@ -0,0 +1,162 @@
+package bankid
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how requests to BankID are retried when they fail with a transient error.
+//
+// Requests are retried on `maintenance`, where BankID guarantees that the request had no effect. The /collect, /cancel and
+// /verify endpoints are also retried on network errors. The /auth, /sign, /phone/auth and /phone/sign endpoints create orders,
+// a request that failed on the network may still have created an order so they are not retried on network errors.
+// `internalError`, `requestTimeout` and TLS errors are never retried.
+type RetryPolicy struct {
+	// Optional: The maximum number of attempts, including the first one.
+	// Default: 3
+	MaxAttempts int `json:"maxAttempts"`
+
+	// Optional: The wait before the first retry, it's multiplied by Multiplier for every following retry.
+	// Default: 250ms
+	InitialBackoff time.Duration `json:"initialBackoff"`
+
+	// Optional: The upper limit of the wait between two attempts.
+	// Default: 2s
+	MaxBackoff time.Duration `json:"maxBackoff"`
+
+	// Optional: The factor the backoff grows with after each attempt.
+	// Default: 2
+	Multiplier float64 `json:"multiplier"`
+
+	// Optional: The fraction (0-1) of the backoff that is randomised to avoid synchronised retries between clients.
+	// Default: 0.2
+	Jitter float64 `json:"jitter"`
+}
+
+// Ensures the retry policy is set based on the defaults or leaves the input unchanged if it's set
+func (p RetryPolicy) UseDefault() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = 250 * time.Millisecond
+	}
+
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = 2 * time.Second
+	}
+
+	if p.Multiplier == 0 {
+		p.Multiplier = 2
+	}
+
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+
+	return p
+}
+
+// backoff returns the wait before the given retry, starting at 1 for the first retry.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	// spread the wait evenly within [d - jitter*d, d + jitter*d]
+	d += d * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(d)
+}
+
+// retry calls f until it succeeds, returns an error that isn't retryable, or the policy or ctx deadline is exhausted.
+// When more than one attempt was made the last error is wrapped in a RetryError.
+func retry[T any](ctx context.Context, policy RetryPolicy, f func() (*T, error)) (*T, error) {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		var r *T
+		r, err = f()
+		if err == nil {
+			return r, nil
+		}
+
+		if attempt >= policy.MaxAttempts || !isRetryable(err) {
+			return nil, wrapAttempts(attempt, err)
+		}
+
+		wait := policy.backoff(attempt)
+
+		// don't wait for a retry that can't complete before the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return nil, wrapAttempts(attempt, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, wrapAttempts(attempt, errors.Join(err, ctx.Err()))
+		case <-timer.C:
+		}
+	}
+}
+
+func wrapAttempts(attempts int, err error) error {
+	if attempts == 1 {
+		return err
+	}
+
+	return RetryError{Attempts: attempts, Err: err}
+}
+
+// isRetryable reports whether a failed request can be sent again without side effects.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+
+	return false
+}
+
+// isTLSError reports whether an error is caused by the TLS handshake or the verification of a certificate.
+func isTLSError(err error) bool {
+	var (
+		verification  *tls.CertificateVerificationError
+		alert         tls.AlertError
+		recordHeader  tls.RecordHeaderError
+		unknownAuth   x509.UnknownAuthorityError
+		invalid       x509.CertificateInvalidError
+		hostname      x509.HostnameError
+		systemRoots   x509.SystemRootsError
+		constraint    x509.ConstraintViolationError
+		insecure      x509.InsecureAlgorithmError
+		unhandledCrit x509.UnhandledCriticalExtension
+	)
+
+	return errors.As(err, &verification) || errors.As(err, &alert) || errors.As(err, &recordHeader) ||
+		errors.As(err, &unknownAuth) || errors.As(err, &invalid) || errors.As(err, &hostname) ||
+		errors.As(err, &systemRoots) || errors.As(err, &constraint) || errors.As(err, &insecure) ||
+		errors.As(err, &unhandledCrit)
+}
+
+// isOrderPath reports whether a request to the path creates a new order at BankID.
+func isOrderPath(path string) bool {
+	switch path {
+	case "/collect", "/cancel", "/verify":
+		return false
+	default:
+		return true
+	}
+}
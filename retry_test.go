@@ -0,0 +1,114 @@
+package bankid
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTLSError(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Millisecond}.UseDefault()
+
+	var attempts atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Write([]byte(`{"orderRef":"ref"}`))
+	}))
+	defer server.Close()
+
+	// the client doesn't trust the certificate of the test server
+	_, err := request[CollectResponse](context.Background(), RequestParameters{
+		Path:   "/collect",
+		Config: &RequestConfig{UrlBase: server.URL, Client: &http.Client{}, Retry: &policy},
+		Body:   CollectRequest{OrderRef: "ref"},
+	})
+
+	var transportErr TransportError
+	if !errors.As(err, &transportErr) || transportErr.Temporary() || transportErr.Retryable() {
+		t.Fatalf("expected a permanent TransportError, got %v", err)
+	}
+
+	var retryErr RetryError
+	if errors.As(err, &retryErr) {
+		t.Errorf("expected the TLS error not to be retried, got %d attempts", retryErr.Attempts)
+	}
+
+	if attempts.Load() != 0 {
+		t.Errorf("expected no request to reach the server, got %d", attempts.Load())
+	}
+
+	for _, err := range []error{
+		x509.UnknownAuthorityError{},
+		x509.HostnameError{Host: "appapi2.test.bankid.com"},
+		tls.AlertError(42), // bad_certificate, the client certificate was rejected
+		&tls.CertificateVerificationError{Err: x509.CertificateInvalidError{Reason: x509.Expired}},
+	} {
+		if (TransportError{Path: "/collect", Err: err}).Retryable() {
+			t.Errorf("expected %T not to be retried", err)
+		}
+	}
+
+	if !(TransportError{Path: "/collect", Err: errors.New("connection reset by peer")}).Retryable() {
+		t.Errorf("expected a network error on /collect to be retried")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Millisecond}.UseDefault()
+
+	for _, tt := range []struct {
+		name             string
+		path             string
+		failures         int32
+		errorCode        ErrorCode
+		expectedAttempts int32
+		expectedErr      bool
+	}{
+		{name: "collect recovers from maintenance", path: "/collect", failures: 2, errorCode: Maintenance, expectedAttempts: 3},
+		{name: "collect is not retried on internal errors", path: "/collect", failures: 5, errorCode: InternalError, expectedAttempts: 1, expectedErr: true},
+		{name: "cancel is not retried on request timeouts", path: "/cancel", failures: 5, errorCode: RequestTimeout, expectedAttempts: 1, expectedErr: true},
+		{name: "auth is not retried on internal errors", path: "/auth", failures: 5, errorCode: InternalError, expectedAttempts: 1, expectedErr: true},
+		{name: "auth is retried on maintenance", path: "/auth", failures: 1, errorCode: Maintenance, expectedAttempts: 2},
+		{name: "invalid parameters are never retried", path: "/cancel", failures: 5, errorCode: InvalidParameters, expectedAttempts: 1, expectedErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte(`{"errorCode":"` + string(tt.errorCode) + `","details":"test"}`))
+					return
+				}
+				w.Write([]byte(`{"orderRef":"ref"}`))
+			}))
+			defer server.Close()
+
+			_, err := request[CollectResponse](context.Background(), RequestParameters{
+				Path:   tt.path,
+				Config: &RequestConfig{UrlBase: server.URL, Client: server.Client(), Retry: &policy},
+				Body:   CollectRequest{OrderRef: "ref"},
+			})
+
+			if attempts.Load() != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, attempts.Load())
+			}
+
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var retryErr RetryError
+			if tt.expectedErr && tt.expectedAttempts > 1 {
+				if !errors.As(err, &retryErr) || retryErr.Attempts != int(tt.expectedAttempts) {
+					t.Errorf("expected RetryError with %d attempts in chain, got %v", tt.expectedAttempts, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package bankid
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SignatureType tells if a signature was created by an auth or a sign order.
+type SignatureType string
+
+const (
+	SignatureTypeAuth SignatureType = "auth"
+	SignatureTypeSign SignatureType = "sign"
+)
+
+// BankIDSignature is the XML signature returned in CompletionData.Signature.
+// Documentation: https://www.bankid.com/en/utvecklare/guider/teknisk-integrationsguide/graenssnittsbeskrivning/collect
+type BankIDSignature struct {
+	// The XML document the user's BankID signed.
+	XML []byte
+
+	// The signature was created by an auth or a sign order.
+	Type SignatureType
+
+	// The text displayed to the user, decoded from base64. Empty for auth orders without userVisibleData.
+	UserVisibleData string
+
+	// The data not displayed to the user, decoded from base64.
+	UserNonVisibleData []byte
+
+	// Information about the RP that started the order.
+	ServerInfo ServerInfo
+
+	// Information about the BankID app that signed.
+	ClientInfo ClientInfo
+
+	// The certificates of KeyInfo, starting with the user's certificate followed by the CAs that issued it.
+	Certificates []*x509.Certificate
+
+	// The algorithm of SignatureValue, e.g. "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256".
+	SignatureMethod string
+
+	// The canonicalization algorithm of SignedInfo.
+	CanonicalizationMethod string
+
+	// The signature value, decoded from base64.
+	SignatureValue []byte
+
+	// The digests of the signed parts of the document.
+	References []SignatureReference
+
+	// BankID signatures carry no time, the signing time is the producedAt time of the OCSP response
+	// BankID fetched when the user signed. Only set when verified with SignatureVerifier.VerifyCompletionData,
+	// after the OCSP response is verified against the issuer of the verified certificate chain.
+	SigningTime time.Time
+}
+
+// ServerInfo describes the RP that started the order.
+type ServerInfo struct {
+	// The distinguished name of the RP's certificate, e.g. "cn=FP Testcert 5,name=Test av BankID".
+	Name string
+
+	// The name of the RP displayed to the user.
+	DisplayName string
+
+	// A random value created by BankID for the order.
+	Nonce []byte
+}
+
+// ClientInfo describes the BankID app that signed.
+type ClientInfo struct {
+	// "Identification" for auth orders and "Signing" for sign orders.
+	FuncID string
+
+	// The version of the BankID app, e.g. "Personal=8.4.0.12&BankIDApp=true".
+	Version string
+}
+
+// SignatureReference is a signed part of the document and its digest.
+type SignatureReference struct {
+	// The Id of the referenced element, e.g. "#bidSignedData".
+	URI string
+
+	// The algorithms applied to the element before the digest was calculated.
+	Transforms []string
+
+	// The algorithm of the digest, e.g. "http://www.w3.org/2001/04/xmlenc#sha256".
+	DigestMethod string
+
+	// The digest, decoded from base64.
+	DigestValue []byte
+}
+
+// UserCertificate returns the certificate of the user's BankID, the first certificate of KeyInfo.
+func (s *BankIDSignature) UserCertificate() *x509.Certificate {
+	if len(s.Certificates) == 0 {
+		return nil
+	}
+
+	return s.Certificates[0]
+}
+
+type xmlSignature struct {
+	XMLName    xml.Name `xml:"Signature"`
+	SignedInfo struct {
+		CanonicalizationMethod xmlAlgorithm `xml:"CanonicalizationMethod"`
+		SignatureMethod        xmlAlgorithm `xml:"SignatureMethod"`
+		References             []struct {
+			URI          string         `xml:"URI,attr"`
+			Transforms   []xmlAlgorithm `xml:"Transforms>Transform"`
+			DigestMethod xmlAlgorithm   `xml:"DigestMethod"`
+			DigestValue  string         `xml:"DigestValue"`
+		} `xml:"Reference"`
+	} `xml:"SignedInfo"`
+	SignatureValue string   `xml:"SignatureValue"`
+	Certificates   []string `xml:"KeyInfo>X509Data>X509Certificate"`
+	SignedData     struct {
+		UserVisibleData    string `xml:"usrVisibleData"`
+		UserNonVisibleData string `xml:"usrNonVisibleData"`
+		ServerInfo         struct {
+			Name        string `xml:"name"`
+			DisplayName string `xml:"displayName"`
+			Nonce       string `xml:"nonce"`
+		} `xml:"srvInfo"`
+		ClientInfo struct {
+			FuncID  string `xml:"funcId"`
+			Version string `xml:"version"`
+		} `xml:"clientInfo"`
+	} `xml:"Object>bankIdSignedData"`
+}
+
+type xmlAlgorithm struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+// ParseSignature decodes the base64 encoded XML signature of CompletionData.Signature.
+// The signature is only parsed, use a SignatureVerifier before its content is trusted.
+func ParseSignature(signature string) (*BankIDSignature, error) {
+	doc, err := decodeBase64(signature)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature: %w", err)
+	}
+
+	var x xmlSignature
+	err = xml.Unmarshal(doc, &x)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signature xml: %w", err)
+	}
+
+	s := &BankIDSignature{
+		XML:                    doc,
+		SignatureMethod:        x.SignedInfo.SignatureMethod.Algorithm,
+		CanonicalizationMethod: x.SignedInfo.CanonicalizationMethod.Algorithm,
+		ClientInfo: ClientInfo{
+			FuncID:  strings.TrimSpace(x.SignedData.ClientInfo.FuncID),
+			Version: decodeText(x.SignedData.ClientInfo.Version),
+		},
+	}
+
+	switch s.ClientInfo.FuncID {
+	case "Identification":
+		s.Type = SignatureTypeAuth
+	case "Signing":
+		s.Type = SignatureTypeSign
+	default:
+		return nil, fmt.Errorf("unknown funcId in signature: %q", s.ClientInfo.FuncID)
+	}
+
+	var userVisibleData, name, displayName []byte
+	for _, f := range []struct {
+		name  string
+		value string
+		dst   *[]byte
+	}{
+		{"SignatureValue", x.SignatureValue, &s.SignatureValue},
+		{"usrVisibleData", x.SignedData.UserVisibleData, &userVisibleData},
+		{"usrNonVisibleData", x.SignedData.UserNonVisibleData, &s.UserNonVisibleData},
+		{"srvInfo name", x.SignedData.ServerInfo.Name, &name},
+		{"srvInfo displayName", x.SignedData.ServerInfo.DisplayName, &displayName},
+		{"srvInfo nonce", x.SignedData.ServerInfo.Nonce, &s.ServerInfo.Nonce},
+	} {
+		*f.dst, err = decodeBase64(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", f.name, err)
+		}
+	}
+
+	s.UserVisibleData = string(userVisibleData)
+	s.ServerInfo.Name = string(name)
+	s.ServerInfo.DisplayName = string(displayName)
+
+	for _, r := range x.SignedInfo.References {
+		digest, err := decodeBase64(r.DigestValue)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding digest of %s: %w", r.URI, err)
+		}
+
+		var transforms []string
+		for _, t := range r.Transforms {
+			transforms = append(transforms, t.Algorithm)
+		}
+
+		s.References = append(s.References, SignatureReference{
+			URI:          r.URI,
+			Transforms:   transforms,
+			DigestMethod: r.DigestMethod.Algorithm,
+			DigestValue:  digest,
+		})
+	}
+
+	if len(x.Certificates) == 0 {
+		return nil, fmt.Errorf("signature has no certificates in KeyInfo")
+	}
+
+	for i, c := range x.Certificates {
+		der, err := decodeBase64(c)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding certificate %d: %w", i, err)
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate %d: %w", i, err)
+		}
+
+		s.Certificates = append(s.Certificates, cert)
+	}
+
+	return s, nil
+}
+
+// ParseSignature parses the signature of the completed order without verifying it.
+func (c CompletionData) ParseSignature() (*BankIDSignature, error) {
+	return ParseSignature(c.Signature)
+}
+
+// decodeBase64 decodes standard base64 that may be split over several lines, as in XML documents.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// decodeText decodes base64 encoded text, text that isn't base64 encoded is returned as it is.
+func decodeText(s string) string {
+	s = strings.TrimSpace(s)
+	if b, err := decodeBase64(s); err == nil {
+		return string(b)
+	}
+
+	return s
+}
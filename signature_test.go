@@ -0,0 +1,238 @@
+package bankid
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testPKI is a root CA, an issuing CA of a bank and a user certificate, like the chain of a BankID.
+type testPKI struct {
+	root      *x509.Certificate
+	issuer    *x509.Certificate
+	issuerKey crypto.Signer
+	user      *x509.Certificate
+	userKey   crypto.Signer
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	create := func(template, parent *x509.Certificate, key, parentKey crypto.Signer) *x509.Certificate {
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+		if err != nil {
+			t.Fatalf("error creating certificate: %v", err)
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("error parsing certificate: %v", err)
+		}
+
+		return cert
+	}
+
+	newKey := func() crypto.Signer {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("error generating key: %v", err)
+		}
+
+		return key
+	}
+
+	ca := func(serial int64, name string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: name, Organization: []string{"Test BankID"}},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(24 * time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+	}
+
+	rootKey, issuerKey, userKey := newKey(), newKey(), newKey()
+
+	root := ca(1, "Test BankID Root CA")
+	root = create(root, root, rootKey, rootKey)
+	issuer := create(ca(2, "Test Bank Customer CA"), root, issuerKey, rootKey)
+	user := create(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Karl Karlsson", SerialNumber: "199510221287"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, issuer, userKey, issuerKey)
+
+	return &testPKI{root: root, issuer: issuer, issuerKey: issuerKey, user: user, userKey: userKey}
+}
+
+// signatureXML returns a signature document in the format of BankID, signed by the user's key.
+func (p *testPKI) signatureXML(funcID string, userVisibleData string, userNonVisibleData []byte) string {
+	b64 := base64.StdEncoding.EncodeToString
+	digest := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return b64(sum[:])
+	}
+
+	keyInfo := fmt.Sprintf(`<KeyInfo xmlns="http://www.w3.org/2000/09/xmldsig#" Id="bidKeyInfo">`+
+		`<X509Data><X509Certificate>%s</X509Certificate><X509Certificate>%s</X509Certificate><X509Certificate>%s</X509Certificate></X509Data>`+
+		`</KeyInfo>`,
+		b64(p.user.Raw), b64(p.issuer.Raw), b64(p.root.Raw),
+	)
+
+	signedData := fmt.Sprintf(`<bankIdSignedData xmlns="http://www.bankid.com/signature/v1.0.0/types" Id="bidSignedData">`+
+		`<usrVisibleData charset="UTF-8" visible="wysiwys">%s</usrVisibleData>`+
+		`<usrNonVisibleData>%s</usrNonVisibleData>`+
+		`<srvInfo><name>%s</name><nonce>%s</nonce><displayName>%s</displayName></srvInfo>`+
+		`<clientInfo><funcId>%s</funcId><version>%s</version></clientInfo>`+
+		`</bankIdSignedData>`,
+		b64([]byte(userVisibleData)), b64(userNonVisibleData),
+		b64([]byte("cn=FP Testcert 5,name=Test av BankID")), b64([]byte("nonce")), b64([]byte("Test av BankID")),
+		funcID, b64([]byte("Personal=8.4.0.12&BankIDApp=true")),
+	)
+
+	// the parts are written in canonical form, so they are signed as they are
+	signedInfo := fmt.Sprintf(`<SignedInfo xmlns="http://www.w3.org/2000/09/xmldsig#">`+
+		`<CanonicalizationMethod Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"></CanonicalizationMethod>`+
+		`<SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"></SignatureMethod>`+
+		`<Reference Type="http://www.bankid.com/signature/v1.0.0/types" URI="#bidSignedData">`+
+		`<Transforms><Transform Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"></Transform></Transforms>`+
+		`<DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></DigestMethod>`+
+		`<DigestValue>%s</DigestValue>`+
+		`</Reference>`+
+		`<Reference URI="#bidKeyInfo">`+
+		`<Transforms><Transform Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"></Transform></Transforms>`+
+		`<DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></DigestMethod>`+
+		`<DigestValue>%s</DigestValue>`+
+		`</Reference>`+
+		`</SignedInfo>`,
+		digest(signedData), digest(keyInfo),
+	)
+
+	// XML signatures hold ECDSA signatures as r and s of 32 bytes each
+	sum := sha256.Sum256([]byte(signedInfo))
+	r, s, err := ecdsa.Sign(rand.Reader, p.userKey.(*ecdsa.PrivateKey), sum[:])
+	if err != nil {
+		panic(err)
+	}
+	signatureValue := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` +
+		`<Signature xmlns="http://www.w3.org/2000/09/xmldsig#">` +
+		signedInfo +
+		`<SignatureValue>` + b64(signatureValue) + `</SignatureValue>` +
+		keyInfo +
+		`<Object>` + signedData + `</Object>` +
+		`</Signature>`
+}
+
+func TestParseSignature(t *testing.T) {
+	pki := newTestPKI(t)
+	doc := pki.signatureXML("Signing", "Jag godkänner avtalet", []byte{0x00, 0xff})
+
+	s, err := ParseSignature(base64.StdEncoding.EncodeToString([]byte(doc)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.Type != SignatureTypeSign || s.UserVisibleData != "Jag godkänner avtalet" || string(s.UserNonVisibleData) != "\x00\xff" {
+		t.Errorf("unexpected signed data: %s %q %x", s.Type, s.UserVisibleData, s.UserNonVisibleData)
+	}
+
+	if s.ServerInfo.DisplayName != "Test av BankID" || string(s.ServerInfo.Nonce) != "nonce" || s.ClientInfo.Version != "Personal=8.4.0.12&BankIDApp=true" {
+		t.Errorf("unexpected server or client info: %+v %+v", s.ServerInfo, s.ClientInfo)
+	}
+
+	if len(s.Certificates) != 3 || s.UserCertificate().Subject.SerialNumber != "199510221287" {
+		t.Errorf("expected the user certificate first, got %d certificates", len(s.Certificates))
+	}
+
+	if len(s.References) != 2 || s.References[1].URI != "#bidKeyInfo" || len(s.References[0].DigestValue) != sha256.Size {
+		t.Errorf("unexpected references: %+v", s.References)
+	}
+}
+
+func TestParseAuthSignature(t *testing.T) {
+	pki := newTestPKI(t)
+	doc := pki.signatureXML("Identification", "", nil)
+
+	// BankID splits long base64 values over several lines
+	encoded := base64.StdEncoding.EncodeToString([]byte(doc))
+	wrapped := encoded[:64] + "\n" + encoded[64:]
+
+	s, err := ParseSignature(wrapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.Type != SignatureTypeAuth || s.UserVisibleData != "" {
+		t.Errorf("expected an auth signature without visible data, got %s %q", s.Type, s.UserVisibleData)
+	}
+
+	_, err = ParseSignature("not base64")
+	if err == nil {
+		t.Errorf("expected an error for an invalid signature")
+	}
+}
+
+func TestCompletionDataSigningTime(t *testing.T) {
+	pki := newTestPKI(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.root)
+
+	signature := base64.StdEncoding.EncodeToString([]byte(pki.signatureXML("Signing", "text", nil)))
+	hash := sha1.Sum([]byte(signature))
+	nonce := append(hash[:], make([]byte, 12)...)
+
+	c := CompletionData{
+		Signature:    signature,
+		OcspResponse: pki.ocspResponse(t, ocsp.Good, nonce, pki.issuer, pki.issuerKey),
+	}
+
+	// the time of an unverified ocsp response isn't used
+	s, err := c.ParseSignature()
+	if err != nil || !s.SigningTime.IsZero() {
+		t.Errorf("expected no signing time of a parsed signature, got %v %v", s, err)
+	}
+
+	s, err = SignatureVerifier{Roots: roots}.VerifyCompletionData(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if time.Since(s.SigningTime) > 2*time.Minute {
+		t.Errorf("expected the signing time of the ocsp response, got %v", s.SigningTime)
+	}
+
+	// a response of another issuer can't set the time the chain is verified at
+	other := newTestPKI(t)
+	forged := c
+	forged.OcspResponse = other.ocspResponse(t, ocsp.Good, nonce, other.issuer, other.issuerKey)
+
+	_, err = SignatureVerifier{Roots: roots}.VerifyCompletionData(forged)
+	if err == nil || !errors.As(err, &OCSPResponderError{}) {
+		t.Errorf("expected an OCSPResponderError, got %v", err)
+	}
+
+	// the time of the verifier is used over the signing time
+	_, err = SignatureVerifier{Roots: roots, Time: pki.user.NotAfter.Add(time.Hour)}.VerifyCompletionData(c)
+	if err == nil || !errors.As(err, &UntrustedCertificateError{}) {
+		t.Errorf("expected an UntrustedCertificateError, got %v", err)
+	}
+}
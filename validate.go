@@ -3,6 +3,8 @@ package bankid
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"regexp"
 	"strings"
 	"unicode/utf8"
 )
@@ -59,6 +61,15 @@ func validateRequired(body RequestBody) ValidateOption {
 				return RequiredInputMissingError{Message: fmt.Sprintf("UserVisibleData is missing or invalid, it's required by BankID for request: %T", v)}
 			}
 
+		case PaymentRequest:
+			if v.EndUserIP == "" {
+				return RequiredInputMissingError{Message: fmt.Sprintf("EndUserIP is missing but required by BankID for request: %T", v)}
+			}
+
+			if v.UserVisibleTransaction.TransactionType == "" || v.UserVisibleTransaction.Recipient.Name == "" {
+				return RequiredInputMissingError{Message: fmt.Sprintf("UserVisibleTransaction requires a TransactionType and a Recipient name for request: %T", v)}
+			}
+
 		}
 		return nil
 	}
@@ -67,7 +78,115 @@ func validateRequired(body RequestBody) ValidateOption {
 func validateEndUserIP(endUserIP string) ValidateOption {
 	return func() error {
 		if valid := isValidIP(endUserIP); !valid {
-			return InputInvalidError{Message: fmt.Sprintf("EndUserIP: %s is invalid", endUserIP)}
+			return InputInvalidError{Message: fmt.Sprintf("EndUserIP: %s is invalid", redactIP(endUserIP))}
+		}
+
+		return nil
+	}
+}
+
+func validateReturnURL(returnURL string) ValidateOption {
+	return func() error {
+		// returnUrl is optional
+		if returnURL == "" {
+			return nil
+		}
+
+		u, err := url.Parse(returnURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return InputInvalidError{Message: fmt.Sprintf("ReturnURL: %s is invalid, it should be an absolute https URL", redactURL(returnURL))}
+		}
+
+		return nil
+	}
+}
+
+func validateDevice(app *App, web *Web) ValidateOption {
+	return func() error {
+		if app != nil && web != nil {
+			return InputInvalidError{Message: "App and Web can't both be set, use the one the user started the order from"}
+		}
+
+		if app != nil {
+			if app.AppIdentifier == "" || app.DeviceOS == "" || app.DeviceModelName == "" || app.DeviceIdentifier == "" {
+				return RequiredInputMissingError{Message: "App requires AppIdentifier, DeviceOS, DeviceModelName and DeviceIdentifier"}
+			}
+		}
+
+		if web != nil {
+			if web.ReferringDomain == "" || web.UserAgent == "" || web.DeviceIdentifier == "" {
+				return RequiredInputMissingError{Message: "Web requires ReferringDomain, UserAgent and DeviceIdentifier"}
+			}
+
+			// an IPv6 address is the only referring domain that may contain a colon
+			if strings.ContainsAny(web.ReferringDomain, ":/?#@ ") && net.ParseIP(web.ReferringDomain) == nil {
+				return InputInvalidError{Message: fmt.Sprintf("ReferringDomain: %s is invalid, it should be a domain name without scheme, port or path", web.ReferringDomain)}
+			}
+		}
+
+		return nil
+	}
+}
+
+func validateTransaction(transaction UserVisibleTransaction) ValidateOption {
+	return func() error {
+		switch transaction.TransactionType {
+		case TransactionTypeCard:
+			if transaction.Money == nil {
+				return RequiredInputMissingError{Message: "Money is missing but required by BankID for card transactions"}
+			}
+		case TransactionTypeNPA:
+			if transaction.Money != nil {
+				return InputInvalidError{Message: "Money can't be set for npa transactions"}
+			}
+		default:
+			return InputInvalidError{Message: fmt.Sprintf("TransactionType: %s is invalid, it should be 'card' or 'npa'", transaction.TransactionType)}
+		}
+
+		if transaction.Money != nil {
+			if !amountPattern.MatchString(transaction.Money.Amount) {
+				return InputInvalidError{Message: fmt.Sprintf("Amount: %s is invalid, it should use a comma as decimal separator and have up to two decimals, e.g. '1249,50'", transaction.Money.Amount)}
+			}
+
+			if !currencyPattern.MatchString(transaction.Money.Currency) {
+				return InputInvalidError{Message: fmt.Sprintf("Currency: %s is invalid, it should be an ISO 4217 code, e.g. 'SEK'", transaction.Money.Currency)}
+			}
+		}
+
+		return nil
+	}
+}
+
+var (
+	amountPattern   = regexp.MustCompile(`^[0-9]+(,[0-9]{1,2})?$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+func validateRiskFlags(riskFlags []RiskFlag) ValidateOption {
+	return func() error {
+		for _, flag := range riskFlags {
+			switch flag {
+			case RiskFlagNewCard, RiskFlagNewCustomer, RiskFlagNewRecipient, RiskFlagHighRiskRecipient, RiskFlagLargeAmount,
+				RiskFlagForeignCurrency, RiskFlagCryptocurrencyPurchase, RiskFlagMoneyTransfer, RiskFlagOverseasTransaction,
+				RiskFlagRecurringPayment, RiskFlagSuspiciousPaymentPattern, RiskFlagOther:
+			default:
+				return InputInvalidError{Message: fmt.Sprintf("RiskFlag: %s is invalid, check BankID for valid risk flags", flag)}
+			}
+		}
+
+		return nil
+	}
+}
+
+func validateIDCardQRCode(qrCode string) ValidateOption {
+	return func() error {
+		if qrCode == "" {
+			return RequiredInputMissingError{Message: "QRCode is missing but required by BankID to verify a digital ID card"}
+		}
+
+		// the animated QR codes of orders start with "bankid." and can't be verified
+		if strings.HasPrefix(qrCode, "bankid.") {
+			return InputInvalidError{Message: "QRCode belongs to an order, only the QR code of a digital ID card can be verified"}
 		}
 
 		return nil
@@ -125,26 +244,9 @@ func validateCertificatePolicies(certificatePolicies []string) ValidateOption {
 
 func validatePersonalNumber(personalNumber string) ValidateOption {
 	return func() error {
-		if personalNumber == "" {
-			return InputInvalidError{Message: "Invalid personnummer, received an empty string"}
-		}
-
-		// remove dash if provided
-		if strings.Contains(personalNumber, "-") {
-			personalNumber = strings.ReplaceAll(personalNumber, "-", "")
-		}
-
-		// assume that personal numbers of length: 12 are YYYYMMDD, format to YYMMDD
-		if len(personalNumber) == 12 {
-			personalNumber = personalNumber[2:]
-		}
-
-		// ensure checksum (kontrollsiffra - last digit) of the personal number is valid
-		err := validateChecksum(personalNumber)
-		if err != nil {
-			return InputInvalidError{Message: fmt.Sprintf("Personnummer: %s is not formatted correctly", personalNumber)}
-		}
-		return nil
+		// the number is normalised to the 12 digits of BankID when the request is processed
+		_, err := ParsePersonnummer(personalNumber)
+		return err
 	}
 }
 
@@ -156,11 +258,15 @@ func validateRequirement(requirement *Requirement) ValidateOption {
 		}
 
 		opts := []ValidateOption{
-			validatePersonalNumber(requirement.PersonalNumber),
 			validateCertificatePolicies(requirement.CertificatePolicies),
 			validateCardReader(requirement.CardReader),
 		}
 
+		// the personal number of a requirement is optional
+		if requirement.PersonalNumber != "" {
+			opts = append(opts, validatePersonalNumber(requirement.PersonalNumber))
+		}
+
 		for _, opt := range opts {
 			err := opt()
 			if err != nil {
@@ -0,0 +1,194 @@
+package bankid
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// APIVersion is a version of the BankID RP API, e.g. "6.0".
+// Each version has its own base path and set of endpoints, and requests are checked against the fields the version supports.
+// Responses aren't versioned, they are decoded into the same types whatever the version.
+type APIVersion string
+
+const (
+	APIVersion60 APIVersion = "6.0"
+
+	// The version used when neither the config nor its URL select one.
+	DefaultAPIVersion = APIVersion60
+)
+
+const (
+	BankIDHost     = "https://appapi2.bankid.com"
+	BankIDTestHost = "https://appapi2.test.bankid.com"
+)
+
+// apiSchema describes the endpoints of an API version and the request fields each endpoint supports.
+type apiSchema struct {
+	// The supported JSON fields of each endpoint's request, nested fields are written as "requirement.pinCode".
+	// Nested objects without listed fields are not checked.
+	endpoints map[string][]string
+}
+
+// Fields shared by the order endpoints of v6.0.
+var (
+	requirementFields60 = []string{
+		"requirement", "requirement.pinCode", "requirement.mrtd", "requirement.cardReader",
+		"requirement.certificatePolicies", "requirement.personalNumber",
+	}
+	userDataFields60 = []string{"userVisibleData", "userNonVisibleData", "userVisibleDataFormat"}
+	deviceFields60   = []string{
+		"app", "app.appIdentifier", "app.deviceOS", "app.deviceModelName", "app.deviceIdentifier",
+		"web", "web.referringDomain", "web.userAgent", "web.deviceIdentifier",
+	}
+)
+
+var apiSchemas = map[APIVersion]apiSchema{
+	APIVersion60: {
+		endpoints: map[string][]string{
+			"/auth":       concat([]string{"endUserIp", "returnUrl", "returnRisk"}, requirementFields60, userDataFields60, deviceFields60),
+			"/sign":       concat([]string{"endUserIp", "returnUrl", "returnRisk"}, requirementFields60, userDataFields60, deviceFields60),
+			"/phone/auth": concat([]string{"personalNumber", "callInitiator", "returnRisk"}, requirementFields60, userDataFields60),
+			"/phone/sign": concat([]string{"personalNumber", "callInitiator", "returnRisk"}, requirementFields60, userDataFields60),
+			"/payment": concat([]string{
+				"endUserIp", "returnUrl", "returnRisk", "riskFlags", "userVisibleTransaction",
+				"userVisibleTransaction.transactionType", "userVisibleTransaction.recipient",
+				"userVisibleTransaction.money", "userVisibleTransaction.riskWarning",
+			}, requirementFields60, userDataFields60, deviceFields60),
+			"/collect": {"orderRef"},
+			"/cancel":  {"orderRef"},
+			"/verify":  {"qrCode"},
+		},
+	},
+}
+
+func concat(fields ...[]string) []string {
+	var all []string
+	for _, f := range fields {
+		all = append(all, f...)
+	}
+
+	return all
+}
+
+// Path returns the base path of the version, e.g. "/rp/v6.0".
+func (v APIVersion) Path() string {
+	return "/rp/v" + string(v)
+}
+
+// URL returns the base URL of the version on a BankID host, e.g. BankIDHost or BankIDTestHost.
+func (v APIVersion) URL(host string) string {
+	return strings.TrimSuffix(host, "/") + v.Path()
+}
+
+// Validate ensures the version is supported by this library
+func (v APIVersion) Validate() error {
+	if _, ok := apiSchemas[v]; !ok {
+		return InputInvalidError{Message: fmt.Sprintf("API version: %s is not supported", v)}
+	}
+
+	return nil
+}
+
+var versionPath = regexp.MustCompile(`/rp/v([0-9]+\.[0-9]+)/?$`)
+
+// versionOf returns the API version of a URL that ends with the base path of a version.
+func versionOf(url string) (APIVersion, bool) {
+	m := versionPath.FindStringSubmatch(url)
+	if m == nil {
+		return "", false
+	}
+
+	return APIVersion(m[1]), true
+}
+
+// resolveEndpoint returns the base URL and API version of the config.
+// Only a bare host, a URL without a path, gets the base path of the version. Any other URL is used as given,
+// e.g. a reverse proxy or a mock server, and a URL ending with a base path must match the version if one is set.
+func resolveEndpoint(rawURL string, version APIVersion) (string, APIVersion, error) {
+	urlVersion, hasVersion := versionOf(rawURL)
+
+	switch {
+	case version == "" && hasVersion:
+		version = urlVersion
+	case version == "":
+		version = DefaultAPIVersion
+	case hasVersion && urlVersion != version:
+		return "", "", InputInvalidError{Message: fmt.Sprintf("URL: %s targets API version %s but Version is %s", rawURL, urlVersion, version)}
+	}
+
+	err := version.Validate()
+	if err != nil {
+		return "", "", err
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", InputInvalidError{Message: fmt.Sprintf("URL: %s is invalid: %v", rawURL, err)}
+	}
+
+	if !hasVersion && (u.Path == "" || u.Path == "/") {
+		rawURL = version.URL(rawURL)
+	}
+
+	return strings.TrimSuffix(rawURL, "/"), version, nil
+}
+
+// check returns an UnsupportedEndpointError or UnsupportedFieldError when the request isn't supported by the version.
+func (v APIVersion) check(path string, body []byte) error {
+	schema, ok := apiSchemas[v]
+	if !ok {
+		return v.Validate()
+	}
+
+	fields, ok := schema.endpoints[path]
+	if !ok {
+		return UnsupportedEndpointError{Version: v, Path: path}
+	}
+
+	supported := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		supported[f] = true
+	}
+
+	return checkFields(v, path, "", body, supported)
+}
+
+func checkFields(v APIVersion, path string, prefix string, body []byte, supported map[string]bool) error {
+	var object map[string]json.RawMessage
+	err := json.Unmarshal(body, &object)
+	if err != nil {
+		// not an object, e.g. an array or a value, so there are no fields to check
+		return nil
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(object)) {
+		field := prefix + key
+		if !supported[field] {
+			return UnsupportedFieldError{Version: v, Path: path, Field: field}
+		}
+
+		if hasNested(supported, field) {
+			err := checkFields(v, path, field+".", object[key], supported)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasNested(supported map[string]bool, field string) bool {
+	for f := range supported {
+		if strings.HasPrefix(f, field+".") {
+			return true
+		}
+	}
+
+	return false
+}
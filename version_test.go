@@ -0,0 +1,115 @@
+package bankid
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		url         string
+		version     APIVersion
+		wantURL     string
+		wantVersion APIVersion
+		wantErr     bool
+	}{
+		{url: BankIDURL, wantURL: BankIDURL, wantVersion: APIVersion60},
+		{url: BankIDTestHost, wantURL: BankIDTestUrl, wantVersion: APIVersion60},
+		{url: BankIDTestHost + "/", version: APIVersion60, wantURL: BankIDTestUrl, wantVersion: APIVersion60},
+		{url: BankIDHost + "/rp/v6.0/", version: APIVersion60, wantURL: BankIDURL, wantVersion: APIVersion60},
+		{url: BankIDHost + "/rp/v5.1", version: APIVersion60, wantErr: true},
+		{url: BankIDHost + "/rp/v9.9", wantErr: true},
+		{url: BankIDHost, version: "9.9", wantErr: true},
+
+		// URLs with a path are used as given, e.g. a reverse proxy, a mock server or a prefixed path
+		{url: "https://proxy.example.com/bankid", wantURL: "https://proxy.example.com/bankid", wantVersion: APIVersion60},
+		{url: "http://127.0.0.1:8080/mock/", wantURL: "http://127.0.0.1:8080/mock", wantVersion: APIVersion60},
+		{url: BankIDTestUrl + "/forbidden/path", wantURL: BankIDTestUrl + "/forbidden/path", wantVersion: APIVersion60},
+	}
+
+	for _, tt := range tests {
+		url, version, err := resolveEndpoint(tt.url, tt.version)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s %s: expected an error", tt.url, tt.version)
+			}
+			continue
+		}
+
+		if err != nil || url != tt.wantURL || version != tt.wantVersion {
+			t.Errorf("%s %s: got %s %s %v", tt.url, tt.version, url, version, err)
+		}
+	}
+}
+
+func TestVersionCheck(t *testing.T) {
+	// a version that predates returnUrl and the phone endpoints
+	apiSchemas["5.9"] = apiSchema{
+		endpoints: map[string][]string{
+			"/auth":    concat([]string{"endUserIp"}, requirementFields60, userDataFields60),
+			"/collect": {"orderRef"},
+		},
+	}
+	t.Cleanup(func() { delete(apiSchemas, "5.9") })
+
+	b := newTestServer(t, `{"orderRef":"ref"}`)
+	b.config.Version = "5.9"
+
+	_, err := b.Auth(context.Background(), AuthRequest{EndUserIP: "192.168.0.1", Requirement: &Requirement{Pincode: true, PersonalNumber: "199510221287"}})
+	if err != nil {
+		t.Errorf("expected supported fields to be accepted, got %v", err)
+	}
+
+	_, err = b.Auth(context.Background(), AuthRequest{EndUserIP: "192.168.0.1", ReturnURL: "https://example.com/done"})
+
+	var unsupportedField UnsupportedFieldError
+	if !errors.As(err, &unsupportedField) || unsupportedField.Field != "returnUrl" || unsupportedField.Version != "5.9" {
+		t.Errorf("expected returnUrl to be unsupported, got %v", err)
+	}
+
+	_, err = b.PhoneAuth(context.Background(), PhoneAuthRequest{PersonalNumber: "199510221287", CallInitiator: "user"})
+
+	var unsupportedEndpoint UnsupportedEndpointError
+	if !errors.As(err, &unsupportedEndpoint) || unsupportedEndpoint.Path != "/phone/auth" {
+		t.Errorf("expected /phone/auth to be unsupported, got %v", err)
+	}
+}
+
+func TestVersionCheckNestedFields(t *testing.T) {
+	err := APIVersion60.check("/auth", []byte(`{"endUserIp":"192.168.0.1","requirement":{"pinCode":true,"unknown":1}}`))
+
+	var unsupportedField UnsupportedFieldError
+	if !errors.As(err, &unsupportedField) || unsupportedField.Field != "requirement.unknown" {
+		t.Errorf("expected requirement.unknown to be unsupported, got %v", err)
+	}
+
+	// every field of the requests of this library is part of v6.0
+	for path, body := range map[string]RequestBody{
+		"/auth":    AuthRequest{EndUserIP: "192.168.0.1", ReturnURL: "https://example.com", ReturnRisk: true, Web: &Web{ReferringDomain: "example.com"}, Requirement: &Requirement{Pincode: true, MRTD: true, CardReader: "class1", CertificatePolicies: []string{"1.2.3.4.5"}, PersonalNumber: "199510221287"}},
+		"/payment": PaymentRequest{EndUserIP: "192.168.0.1", UserVisibleTransaction: UserVisibleTransaction{TransactionType: TransactionTypeCard, Money: &Money{Amount: "1", Currency: "SEK"}, RiskWarning: "warning"}, RiskFlags: []RiskFlag{RiskFlagOther}},
+		"/verify":  VerifyRequest{QRCode: "BANKIDF.code"},
+	} {
+		b, _ := body.Marshal()
+		err := APIVersion60.check(path, b)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+		}
+	}
+}
+
+func TestUseDefaultCA(t *testing.T) {
+	c := Config{URL: BankIDTestHost, Certificate: P12Cert{Certificate: P12TestCertificate, Passphrase: BankIDTestPassphrase}}
+	c.UseDefault()
+
+	if string(c.CA()) != string(CATestCertificate) {
+		t.Errorf("expected the test CA to be used for the test host")
+	}
+
+	c = Config{Version: APIVersion60, Certificate: PEMCert{Certificate: "cert", Passphrase: "pass"}}
+	c.UseDefault()
+
+	if c.URL != BankIDURL || string(c.CA()) != string(CAProdCertificate) {
+		t.Errorf("expected the production URL and CA, got %s", c.URL)
+	}
+}
@@ -0,0 +1,263 @@
+package bankid
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// The parts of the document every BankID signature must sign, with the path of the referenced element.
+var signedReferences = []struct {
+	uri  string
+	path []string
+}{
+	{"#bidSignedData", []string{"Signature", "Object", "bankIdSignedData"}},
+	{"#bidKeyInfo", []string{"Signature", "KeyInfo"}},
+}
+
+var digestMethods = map[string]crypto.Hash{
+	"http://www.w3.org/2001/04/xmlenc#sha256":       crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#sha384": crypto.SHA384,
+	"http://www.w3.org/2001/04/xmlenc#sha512":       crypto.SHA512,
+}
+
+var signatureMethods = map[string]crypto.Hash{
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256":   crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha384":   crypto.SHA384,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512":   crypto.SHA512,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha384": crypto.SHA384,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512": crypto.SHA512,
+}
+
+// SignatureVerifier verifies the XML signature of completed orders offline, e.g. before a signature is stored as evidence.
+// It checks the digests of the signed data and the certificates, the signature value, and the certificate chain of the user.
+//
+// Example:
+//
+//	verifier := bankid.SignatureVerifier{Roots: roots}
+//
+//	signature, err := verifier.VerifyCompletionData(collectResponse.CompletionData)
+//	var mismatch bankid.DigestMismatchError
+//	if errors.As(err, &mismatch) {
+//		// the signed data was changed after it was signed
+//	}
+type SignatureVerifier struct {
+	// Required: The root certificates the user certificates are issued under, the BankID Root CA of the test or production environment.
+	// These are not the SSL roots of the BankID API in CATestCertificate and CAProdCertificate.
+	Roots *x509.CertPool
+
+	// Optional: The time the certificate chain is verified at.
+	// Default: the signing time of the verified OCSP response in VerifyCompletionData, otherwise the current time
+	Time time.Time
+}
+
+// VerifyCompletionData parses and verifies the signature of a completed order. When the order has an OCSP response,
+// it's verified with VerifyOCSPResponse and the certificate chain is verified at its producedAt time, the signing time.
+func (v SignatureVerifier) VerifyCompletionData(c CompletionData) (*BankIDSignature, error) {
+	s, err := c.ParseSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.verifySignature(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.OcspResponse == "" {
+		err = v.verifyChain(s, v.at(time.Now()), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	}
+
+	r, err := VerifyOCSPResponse(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// the time of the response is only trusted when its issuer is part of the verified chain
+	err = v.verifyChain(s, v.at(r.ProducedAt), issuerOf(s))
+	if err != nil {
+		return nil, err
+	}
+
+	s.SigningTime = r.ProducedAt
+	return s, nil
+}
+
+// Verify verifies a parsed signature, with the certificate chain verified at the verifier's Time or the current time.
+// Failures are returned as an UnsupportedAlgorithmError, MissingReferenceError, DigestMismatchError, SignatureValueError
+// or UntrustedCertificateError.
+func (v SignatureVerifier) Verify(s *BankIDSignature) error {
+	err := v.verifySignature(s)
+	if err != nil {
+		return err
+	}
+
+	return v.verifyChain(s, v.at(time.Now()), nil)
+}
+
+// verifySignature verifies the digests and the signature value of a signature.
+func (v SignatureVerifier) verifySignature(s *BankIDSignature) error {
+	if v.Roots == nil {
+		return RequiredInputMissingError{Message: "Roots are missing but required to verify the certificate chain of the signature"}
+	}
+
+	if s.UserCertificate() == nil {
+		return UntrustedCertificateError{Err: fmt.Errorf("signature has no certificates")}
+	}
+
+	if s.CanonicalizationMethod != c14nAlgorithm {
+		return UnsupportedAlgorithmError{Algorithm: s.CanonicalizationMethod}
+	}
+
+	err := verifyReferences(s)
+	if err != nil {
+		return err
+	}
+
+	signedInfo, _, err := canonicalElement(s.XML, "Signature", "SignedInfo")
+	if err != nil {
+		return SignatureValueError{Err: err}
+	}
+
+	err = verifySignatureValue(s.UserCertificate(), s.SignatureMethod, signedInfo, s.SignatureValue)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// verifyReferences ensures the signed data and the certificates are referenced and have the signed digests.
+func verifyReferences(s *BankIDSignature) error {
+	for _, signed := range signedReferences {
+		uri := signed.uri
+
+		var reference *SignatureReference
+		for i := range s.References {
+			if s.References[i].URI == uri {
+				if reference != nil {
+					return MissingReferenceError{URI: uri}
+				}
+				reference = &s.References[i]
+			}
+		}
+
+		if reference == nil {
+			return MissingReferenceError{URI: uri}
+		}
+
+		for _, transform := range reference.Transforms {
+			if transform != c14nAlgorithm {
+				return UnsupportedAlgorithmError{Algorithm: transform}
+			}
+		}
+
+		hash, ok := digestMethods[reference.DigestMethod]
+		if !ok {
+			return UnsupportedAlgorithmError{Algorithm: reference.DigestMethod}
+		}
+
+		// the referenced element must be the one the signed data is parsed from
+		canonical, id, err := canonicalElement(s.XML, signed.path...)
+		if err != nil || "#"+id != uri {
+			return MissingReferenceError{URI: uri}
+		}
+
+		h := hash.New()
+		h.Write(canonical)
+		if subtle.ConstantTimeCompare(h.Sum(nil), reference.DigestValue) != 1 {
+			return DigestMismatchError{URI: uri}
+		}
+	}
+
+	return nil
+}
+
+// verifySignatureValue verifies the signature of the canonical SignedInfo with the key of the user's certificate.
+func verifySignatureValue(cert *x509.Certificate, method string, signedInfo []byte, signature []byte) error {
+	hash, ok := signatureMethods[method]
+	if !ok {
+		return UnsupportedAlgorithmError{Algorithm: method}
+	}
+
+	h := hash.New()
+	h.Write(signedInfo)
+	digest := h.Sum(nil)
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err := rsa.VerifyPKCS1v15(key, hash, digest, signature)
+		if err != nil {
+			return SignatureValueError{Err: err}
+		}
+
+	case *ecdsa.PublicKey:
+		// XML signatures hold the ECDSA signature as r and s of equal length, not as ASN.1
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return SignatureValueError{Err: fmt.Errorf("ecdsa signature has %d bytes, expected %d", len(signature), 2*size)}
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return SignatureValueError{Err: fmt.Errorf("ecdsa verification error")}
+		}
+
+	default:
+		return UnsupportedAlgorithmError{Algorithm: fmt.Sprintf("%T", cert.PublicKey)}
+	}
+
+	return nil
+}
+
+// at returns the time the chain is verified at, the verifier's Time or the given time.
+func (v SignatureVerifier) at(t time.Time) time.Time {
+	if !v.Time.IsZero() {
+		return v.Time
+	}
+
+	return t
+}
+
+// verifyChain builds the chain from the user's certificate to one of the roots at a time, using the other certificates of KeyInfo as intermediates.
+// When an issuer is given, it must be the issuer of the user's certificate in the chain.
+func (v SignatureVerifier) verifyChain(s *BankIDSignature, at time.Time, issuer *x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range s.Certificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := s.UserCertificate().Verify(x509.VerifyOptions{
+		Roots:         v.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return UntrustedCertificateError{Err: err}
+	}
+
+	if issuer == nil {
+		return nil
+	}
+
+	for _, chain := range chains {
+		if len(chain) > 1 && chain[1].Equal(issuer) {
+			return nil
+		}
+	}
+
+	return UntrustedCertificateError{Err: fmt.Errorf("the issuer of the ocsp response is not in the verified chain")}
+}
@@ -0,0 +1,157 @@
+package bankid
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	pki := newTestPKI(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.root)
+
+	verifier := SignatureVerifier{Roots: roots}
+	doc := pki.signatureXML("Signing", "Jag godkänner avtalet", []byte("document-hash"))
+
+	s, err := verifier.VerifyCompletionData(CompletionData{Signature: base64.StdEncoding.EncodeToString([]byte(doc))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.UserVisibleData != "Jag godkänner avtalet" {
+		t.Errorf("unexpected visible data: %q", s.UserVisibleData)
+	}
+
+	b64 := base64.StdEncoding.EncodeToString
+	tests := []struct {
+		name    string
+		doc     string
+		roots   *x509.CertPool
+		wantErr any
+	}{
+		{
+			name:    "changed visible data",
+			doc:     strings.Replace(doc, b64([]byte("Jag godkänner avtalet")), b64([]byte("Jag godkänner inte")), 1),
+			wantErr: &DigestMismatchError{},
+		},
+		{
+			name:    "changed signature value",
+			doc:     strings.Replace(doc, "<SignatureValue>", "<SignatureValue>AAAA", 1),
+			wantErr: &SignatureValueError{},
+		},
+		{
+			name:    "second signed data",
+			doc:     strings.Replace(doc, "</Signature>", "<Object><bankIdSignedData></bankIdSignedData></Object></Signature>", 1),
+			wantErr: &MissingReferenceError{},
+		},
+		{
+			name:    "unsigned key info",
+			doc:     strings.Replace(doc, `<Reference URI="#bidKeyInfo">`, `<Reference URI="#other">`, 1),
+			wantErr: &MissingReferenceError{},
+		},
+		{
+			name:    "untrusted root",
+			doc:     doc,
+			roots:   x509.NewCertPool(),
+			wantErr: &UntrustedCertificateError{},
+		},
+	}
+
+	for _, tt := range tests {
+		v := verifier
+		if tt.roots != nil {
+			v.Roots = tt.roots
+		}
+
+		_, err := v.VerifyCompletionData(CompletionData{Signature: base64.StdEncoding.EncodeToString([]byte(tt.doc))})
+		if err == nil || !errors.As(err, tt.wantErr) {
+			t.Errorf("%s: expected %T, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestVerifySignatureWithoutRoots(t *testing.T) {
+	pki := newTestPKI(t)
+	doc := pki.signatureXML("Signing", "Jag godkänner avtalet", nil)
+
+	_, err := SignatureVerifier{}.VerifyCompletionData(CompletionData{Signature: base64.StdEncoding.EncodeToString([]byte(doc))})
+	if err == nil || !errors.As(err, &RequiredInputMissingError{}) {
+		t.Errorf("expected a RequiredInputMissingError, got %v", err)
+	}
+}
+
+func TestVerifySignatureCanonicalization(t *testing.T) {
+	pki := newTestPKI(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.root)
+
+	doc := pki.signatureXML("Identification", "", nil)
+
+	// formatting that canonicalization removes doesn't break the signature
+	for old, new := range map[string]string{
+		`<Reference Type="http://www.bankid.com/signature/v1.0.0/types" URI="#bidSignedData">`: `<Reference  URI="#bidSignedData"` + "\n" + ` Type="http://www.bankid.com/signature/v1.0.0/types" >`,
+		`<DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></DigestMethod>`:    `<DigestMethod Algorithm='http://www.w3.org/2001/04/xmlenc#sha256'/>`,
+		`<usrVisibleData charset="UTF-8" visible="wysiwys">`:                                   `<usrVisibleData visible="wysiwys" charset="UTF-8"><!-- comment -->`,
+		`<KeyInfo xmlns="http://www.w3.org/2000/09/xmldsig#" Id="bidKeyInfo">`:                 `<KeyInfo Id="bidKeyInfo">`,
+		`<Object>`: "\n<Object>\n",
+	} {
+		doc = strings.ReplaceAll(doc, old, new)
+	}
+
+	_, err := SignatureVerifier{Roots: roots}.VerifyCompletionData(CompletionData{Signature: base64.StdEncoding.EncodeToString([]byte(doc))})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCanonicalElement(t *testing.T) {
+	doc := `<?xml version="1.0"?>` +
+		`<a:root xmlns:a="urn:a" xmlns="urn:default" xml:lang="sv">` +
+		`<inner z="1" a:y="2" b="x&#9;&quot;"><leaf xmlns=""/>text &amp; &lt; &gt;<![CDATA[<c>]]></inner>` +
+		`</a:root>`
+
+	got, _, err := canonicalElement([]byte(doc), "root", "inner")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `<inner xmlns="urn:default" xmlns:a="urn:a" b="x&#x9;&quot;" z="1" xml:lang="sv" a:y="2">` +
+		`<leaf xmlns=""></leaf>text &amp; &lt; &gt;&lt;c&gt;</inner>`
+	if string(got) != want {
+		t.Errorf("unexpected canonical form:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestVerifyRSASignatureValue(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+
+	signedInfo := []byte(`<SignedInfo xmlns="http://www.w3.org/2000/09/xmldsig#"></SignedInfo>`)
+	sum := sha256.Sum256(signedInfo)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatalf("error signing: %v", err)
+	}
+
+	cert := &x509.Certificate{PublicKey: &key.PublicKey}
+	err = verifySignatureValue(cert, "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256", signedInfo, signature)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = verifySignatureValue(cert, "http://www.w3.org/2000/09/xmldsig#rsa-sha1", signedInfo, signature)
+
+	var unsupported UnsupportedAlgorithmError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("expected sha1 to be unsupported, got %v", err)
+	}
+}